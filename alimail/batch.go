@@ -0,0 +1,85 @@
+package alimail
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// maxIdsPerRequest listByIds 接口单次最多支持的 id 数
+	maxIdsPerRequest = 100
+	// listByIdsConcurrency 分片并发请求数，实际速率仍受全局限速器约束
+	listByIdsConcurrency = 4
+)
+
+// listByIdsChunked 将 id 去重后按 100 个一组并发获取，结果按 id 在输入中首次出现的顺序返回，
+// 未查到的 id 放入 notFound。任一分片失败时取消其它分片并返回错误。
+func listByIdsChunked[T any](ctx context.Context, ids []string, fetch func(context.Context, []string) ([]T, error), idOf func(T) string) (items []T, notFound []string, err error) {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return nil, nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		found    = make(map[string]T, len(unique))
+		firstErr error
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, listByIdsConcurrency)
+	)
+	for start := 0; start < len(unique); start += maxIdsPerRequest {
+		chunk := unique[start:min(start+maxIdsPerRequest, len(unique))]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			list, err := fetch(ctx, chunk)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			for _, item := range list {
+				found[idOf(item)] = item
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	items = make([]T, 0, len(found))
+	for _, id := range unique {
+		if item, ok := found[id]; ok {
+			items = append(items, item)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+	return items, notFound, nil
+}
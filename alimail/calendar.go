@@ -0,0 +1,268 @@
+package alimail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CalendarService 日历服务
+type CalendarService struct{ *Client }
+
+// Calendar 日历信息
+type Calendar struct {
+	ID        string `json:"id"`        // 日历ID
+	Name      string `json:"name"`      // 日历名称
+	Color     string `json:"color"`     // 日历颜色
+	IsDefault bool   `json:"isDefault"` // 是否默认日历
+	CanEdit   bool   `json:"canEdit"`   // 当前用户是否可编辑
+}
+
+// EventDateTime 日程时间，dateTime 为不带时区的本地时间，时区由 timeZone 指定
+type EventDateTime struct {
+	DateTime string `json:"dateTime"` // 格式 2006-01-02T15:04:05
+	TimeZone string `json:"timeZone"` // IANA 时区名，如 Asia/Shanghai
+}
+
+const eventDateTimeLayout = "2006-01-02T15:04:05"
+
+// NewEventDateTime 根据 time.Time 构造日程时间
+func NewEventDateTime(t time.Time) EventDateTime {
+	return EventDateTime{DateTime: t.Format(eventDateTimeLayout), TimeZone: t.Location().String()}
+}
+
+// Time 将日程时间解析为 time.Time，时区为空时按 UTC 处理
+func (e EventDateTime) Time() (time.Time, error) {
+	loc := time.UTC
+	if e.TimeZone != "" {
+		l, err := loadLocation(e.TimeZone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+	return time.ParseInLocation(eventDateTimeLayout, e.DateTime, loc)
+}
+
+type RecurrencePatternType string
+
+// 重复规则类型
+const (
+	RecurrenceDaily           RecurrencePatternType = "daily"           // 每天
+	RecurrenceWeekly          RecurrencePatternType = "weekly"          // 每周
+	RecurrenceAbsoluteMonthly RecurrencePatternType = "absoluteMonthly" // 每月的某一天
+	RecurrenceRelativeMonthly RecurrencePatternType = "relativeMonthly" // 每月的第几个星期几
+	RecurrenceAbsoluteYearly  RecurrencePatternType = "absoluteYearly"  // 每年的某月某日
+)
+
+type RecurrenceRangeType string
+
+// 重复范围类型
+const (
+	RecurrenceRangeNoEnd    RecurrenceRangeType = "noEnd"    // 永不结束
+	RecurrenceRangeEndDate  RecurrenceRangeType = "endDate"  // 截止到某天
+	RecurrenceRangeNumbered RecurrenceRangeType = "numbered" // 重复指定次数
+)
+
+// Recurrence 日程重复规则
+type Recurrence struct {
+	Pattern struct {
+		Type       RecurrencePatternType `json:"type"`                 // 重复类型
+		Interval   int                   `json:"interval"`             // 间隔
+		DaysOfWeek []string              `json:"daysOfWeek,omitempty"` // 星期几，如 monday
+		DayOfMonth int                   `json:"dayOfMonth,omitempty"` // 每月第几天
+		Month      int                   `json:"month,omitempty"`      // 月份
+		Index      string                `json:"index,omitempty"`      // 第几周：first/second/third/fourth/last
+	} `json:"pattern"`
+	Range struct {
+		Type                RecurrenceRangeType `json:"type"`                          // 范围类型
+		StartDate           string              `json:"startDate"`                     // 开始日期 2006-01-02
+		EndDate             string              `json:"endDate,omitempty"`             // 结束日期 2006-01-02
+		NumberOfOccurrences int                 `json:"numberOfOccurrences,omitempty"` // 重复次数
+	} `json:"range"`
+	ExceptionDates []string `json:"exceptionDates,omitempty"` // 被排除的日期 2006-01-02
+}
+
+// Attendee 日程参与人
+type Attendee struct {
+	Email          string `json:"email"`                    // 邮箱
+	Name           string `json:"name,omitempty"`           // 姓名
+	Type           string `json:"type,omitempty"`           // required/optional/resource
+	ResponseStatus string `json:"responseStatus,omitempty"` // none/accepted/declined/tentative
+}
+
+// CalendarEvent 日程信息
+type CalendarEvent struct {
+	ID                         string        `json:"id,omitempty"`                         // 日程ID
+	UID                        string        `json:"uid,omitempty"`                        // iCalendar UID
+	Summary                    string        `json:"summary"`                              // 主题
+	Description                string        `json:"description,omitempty"`                // 描述
+	Location                   string        `json:"location,omitempty"`                   // 地点
+	Start                      EventDateTime `json:"start"`                                // 开始时间
+	End                        EventDateTime `json:"end"`                                  // 结束时间
+	IsAllDay                   bool          `json:"isAllDay"`                             // 是否全天
+	Organizer                  *Attendee     `json:"organizer,omitempty"`                  // 组织者
+	Attendees                  []Attendee    `json:"attendees,omitempty"`                  // 参与人
+	Recurrence                 *Recurrence   `json:"recurrence,omitempty"`                 // 重复规则
+	IsReminderOn               bool          `json:"isReminderOn"`                         // 是否提醒
+	ReminderMinutesBeforeStart int           `json:"reminderMinutesBeforeStart,omitempty"` // 提前提醒的分钟数
+	ShowAs                     string        `json:"showAs,omitempty"`                     // free/busy/tentative/oof
+	CreatedTime                time.Time     `json:"createdTime"`                          // 创建时间
+	LastModifiedTime           time.Time     `json:"lastModifiedTime"`                     // 最后修改时间
+}
+
+type listCalendarsRsp struct {
+	Calendars []Calendar `json:"calendars"`
+}
+
+// List 列出用户的全部日历
+func (c *CalendarService) List(ctx context.Context, email string) ([]Calendar, error) {
+	if email == "" {
+		return nil, fmt.Errorf("email is required")
+	}
+	path := fmt.Sprintf("/v2/users/%s/calendars", email)
+
+	resp, err := c.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj listCalendarsRsp
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return dataObj.Calendars, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// ListEventsReq 查询日程列表的参数
+type ListEventsReq struct {
+	Email      string    // 用户邮箱
+	CalendarID string    // 日历ID
+	StartTime  time.Time // 查询开始时间
+	EndTime    time.Time // 查询结束时间
+}
+
+type listEventsRsp struct {
+	Events []CalendarEvent `json:"events"`
+}
+
+// ListEvents 查询某个时间范围内的日程，重复日程只返回主日程
+func (c *CalendarService) ListEvents(ctx context.Context, req ListEventsReq) ([]CalendarEvent, error) {
+	if req.Email == "" || req.CalendarID == "" {
+		return nil, fmt.Errorf("email and calendarId are required")
+	}
+	query := url.Values{}
+	query.Set("startTime", req.StartTime.Format(time.RFC3339))
+	query.Set("endTime", req.EndTime.Format(time.RFC3339))
+	path := fmt.Sprintf("/v2/users/%s/calendars/%s/events?%s", req.Email, req.CalendarID, query.Encode())
+
+	resp, err := c.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj listEventsRsp
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return dataObj.Events, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// GetEvent 获取单个日程
+func (c *CalendarService) GetEvent(ctx context.Context, email, calendarID, eventID string) (*CalendarEvent, error) {
+	if email == "" || calendarID == "" || eventID == "" {
+		return nil, fmt.Errorf("email, calendarId and eventId are required")
+	}
+	path := fmt.Sprintf("/v2/users/%s/calendars/%s/events/%s", email, calendarID, eventID)
+
+	resp, err := c.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj CalendarEvent
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// CreateEvent 创建日程
+func (c *CalendarService) CreateEvent(ctx context.Context, email, calendarID string, event CalendarEvent) (*CalendarEvent, error) {
+	if email == "" || calendarID == "" {
+		return nil, fmt.Errorf("email and calendarId are required")
+	}
+	path := fmt.Sprintf("/v2/users/%s/calendars/%s/events", email, calendarID)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doRequest(ctx, MethodPost, path, BaseHeader, body)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj CalendarEvent
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// UpdateEvent 更新日程
+func (c *CalendarService) UpdateEvent(ctx context.Context, email, calendarID string, event CalendarEvent) error {
+	if email == "" || calendarID == "" || event.ID == "" {
+		return fmt.Errorf("email, calendarId and event id are required")
+	}
+	path := fmt.Sprintf("/v2/users/%s/calendars/%s/events/%s", email, calendarID, event.ID)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doRequest(ctx, MethodPatch, path, BaseHeader, body)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
+
+// DeleteEvent 删除日程
+func (c *CalendarService) DeleteEvent(ctx context.Context, email, calendarID, eventID string) error {
+	if email == "" || calendarID == "" || eventID == "" {
+		return fmt.Errorf("email, calendarId and eventId are required")
+	}
+	path := fmt.Sprintf("/v2/users/%s/calendars/%s/events/%s", email, calendarID, eventID)
+
+	resp, err := c.doRequest(ctx, MethodDelete, path, BaseHeader, nil)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
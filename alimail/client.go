@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 type Client struct {
 	appID     string
 	appSecret string
+	baseURL   string
 
 	token       string
 	tokenExpiry time.Time
@@ -26,6 +28,11 @@ type Client struct {
 
 	httpClient *http.Client
 
+	passwordPolicy *PasswordPolicy // 为 nil 时不在本地校验密码
+	lifecycleAudit func(ctx context.Context, r UserLifecycleResult)
+
+	deptCreateLocks sync.Map // 按 父部门ID/名称 串行化 EnsurePath 中的创建
+
 	// Services
 	Domain              *DomainService
 	User                *UserService
@@ -36,6 +43,9 @@ type Client struct {
 	Message             *MessageService
 	SharedContact       *SharedContactService
 	SharedContactFolder *SharedContactFolderService
+	Calendar            *CalendarService
+	MailboxSettings     *MailboxSettingsService
+	Directory           *DirectoryService
 }
 
 // NewClient 创建一个新的Client实例
@@ -43,6 +53,7 @@ func NewClient(appID, appSecret string) *Client {
 	c := &Client{
 		appID:      appID,
 		appSecret:  appSecret,
+		baseURL:    BaseUrl,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
 	}
 	c.Domain = &DomainService{c}
@@ -54,9 +65,45 @@ func NewClient(appID, appSecret string) *Client {
 	c.Message = &MessageService{c}
 	c.SharedContact = &SharedContactService{c}
 	c.SharedContactFolder = &SharedContactFolderService{c}
+	c.Calendar = &CalendarService{c}
+	c.MailboxSettings = &MailboxSettingsService{c}
+	c.Directory = &DirectoryService{c}
 	return c
 }
 
+// SetBaseURL 修改接口地址，如使用其它区域的接入点或在测试中指向本地服务
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetPasswordPolicy 设置创建用户、重置和修改密码时在本地校验使用的密码策略，
+// 默认不在本地校验，如需启用可传入 DefaultPasswordPolicy
+func (c *Client) SetPasswordPolicy(p PasswordPolicy) {
+	c.passwordPolicy = &p
+}
+
+// PasswordPolicy 返回当前使用的密码策略，未设置时返回零值策略，仍可用于生成随机密码
+func (c *Client) PasswordPolicy() PasswordPolicy {
+	if c.passwordPolicy == nil {
+		return PasswordPolicy{}
+	}
+	return *c.passwordPolicy
+}
+
+// SetLifecycleAudit 设置帐号生命周期操作的审计回调，每次冻结、解冻、隐藏或取消隐藏（包括批量操作中的每个帐号）
+// 完成后都会以操作原因和结果调用一次，可用于写入审计日志；批量操作时会被并发调用
+func (c *Client) SetLifecycleAudit(fn func(ctx context.Context, r UserLifecycleResult)) {
+	c.lifecycleAudit = fn
+}
+
+// checkPassword 未设置密码策略时不做校验
+func (c *Client) checkPassword(password string, pc PasswordContext) error {
+	if c.passwordPolicy == nil {
+		return nil
+	}
+	return c.passwordPolicy.Check(password, pc)
+}
+
 // TokenResponse 表示获取Token的响应结构
 type TokenResponse struct {
 	TokenType   string `json:"token_type"`
@@ -89,7 +136,7 @@ func (c *Client) getToken(ctx context.Context) (string, error) {
 	data.Set("client_id", c.appID)
 	data.Set("client_secret", c.appSecret)
 
-	req, err := http.NewRequestWithContext(ctx, MethodPost, TokenURL, bytes.NewBufferString(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, MethodPost, c.baseURL+tokenPath, bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		return "", err
 	}
@@ -153,7 +200,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, headers map
 	}
 
 	// 构建完整URL
-	fullURL := BaseUrl + path
+	fullURL := c.baseURL + path
 	req, err := http.NewRequestWithContext(ctx, method, fullURL, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
@@ -179,3 +226,33 @@ func (c *Client) doRequest(ctx context.Context, method, path string, headers map
 
 	return resp, nil
 }
+
+// doJSON 发送 JSON 请求并在成功时将响应解析到 out，in 或 out 为 nil 时分别表示无请求体、忽略响应体
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = b
+	}
+
+	resp, err := c.doRequest(ctx, method, path, BaseHeader, body)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if out == nil {
+			return nil
+		}
+		// 部分接口成功时不返回内容
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	}
+	return parseAPIError(resp)
+}
@@ -9,7 +9,9 @@ import (
 
 const (
 	BaseUrl  = "https://alimail-cn.aliyuncs.com"
-	TokenURL = BaseUrl + "/oauth2/v2.0/token"
+	TokenURL = BaseUrl + tokenPath
+
+	tokenPath = "/oauth2/v2.0/token"
 
 	SchemeHTTP  = "http"
 	SchemeHTTPS = "https"
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/http"
 	"time"
 )
@@ -11,6 +12,9 @@ import (
 // DepartmentService 部门服务
 type DepartmentService struct{ *Client }
 
+// RootDepartmentID 根部门ID
+const RootDepartmentID = "$root"
+
 type Department struct {
 	ID                       string    `json:"id"`                       // 部门ID，根部门id为$root
 	Name                     string    `json:"name"`                     // 部门名称
@@ -49,6 +53,29 @@ func (d *DepartmentService) Get(ctx context.Context, deptId string) (*Organizati
 	return nil, parseAPIError(resp)
 }
 
+// GetDepartment 获取部门信息，需要传入部门ID，其中根部门ID为$root；与 Get 不同，返回完整的部门字段
+func (d *DepartmentService) GetDepartment(ctx context.Context, deptId string) (*Department, error) {
+	if deptId == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	path := fmt.Sprintf("/v2/departments/%s", deptId)
+
+	resp, err := d.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj Department
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
 type listDepartmentByIdsRsp struct {
 	Departments []Department `json:"departments"`
 }
@@ -83,6 +110,12 @@ func (d *DepartmentService) ListByIds(ctx context.Context, ids []string) ([]Depa
 	return nil, parseAPIError(resp)
 }
 
+// ListAllByIds 根据任意数量的部门 id 批量获取部门信息，内部按 100 个一组并发请求，
+// 返回结果与输入 id 的顺序一致（重复 id 只返回一次），不存在的 id 放入 notFound
+func (d *DepartmentService) ListAllByIds(ctx context.Context, ids []string) (depts []Department, notFound []string, err error) {
+	return listByIdsChunked(ctx, ids, d.ListByIds, func(dept Department) string { return dept.ID })
+}
+
 type BaseModifyReq struct {
 	Name                     string   `json:"name"`                     // 部门名称
 	ParentID                 string   `json:"parentId"`                 // 父部门 id
@@ -122,6 +155,7 @@ func (u *DepartmentService) Create(ctx context.Context, req CreateDepartmentReq)
 	return nil, parseAPIError(resp)
 }
 
+// UpdateDepartmentReq 更新部门的参数，会发送全部字段；只修改部分字段时请使用 Patch
 type UpdateDepartmentReq struct {
 	ID string `json:"id"`
 	BaseModifyReq
@@ -235,3 +269,19 @@ func (d *DepartmentService) GetDepartmentDepts(ctx context.Context, req ListDepa
 	}
 	return rst, parseAPIError(resp)
 }
+
+// AllUsers 遍历部门内的全部用户（仅直属），自动处理分页
+func (d *DepartmentService) AllUsers(ctx context.Context, deptId string, opts ...PaginatorOption) iter.Seq2[User, error] {
+	return NewPaginator(func(ctx context.Context, offset, limit int) ([]User, int, error) {
+		rsp, err := d.GetDepartmentUsers(ctx, ListDepartmentUsersReq{ID: deptId, Offset: offset, Limit: limit})
+		return rsp.Users, rsp.Total, err
+	}, opts...).All(ctx)
+}
+
+// AllDepts 遍历部门下的全部直属子部门，自动处理分页
+func (d *DepartmentService) AllDepts(ctx context.Context, deptId string, opts ...PaginatorOption) iter.Seq2[Department, error] {
+	return NewPaginator(func(ctx context.Context, offset, limit int) ([]Department, int, error) {
+		rsp, err := d.GetDepartmentDepts(ctx, ListDepartmentDeptsReq{ID: deptId, Offset: offset, Limit: limit})
+		return rsp.Departments, rsp.Total, err
+	}, opts...).All(ctx)
+}
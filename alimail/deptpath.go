@@ -0,0 +1,102 @@
+package alimail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 按名称路径（如 "研发中心/平台部/SRE"）定位和创建部门
+
+// ErrDepartmentNotFound 按路径找不到部门
+var ErrDepartmentNotFound = errors.New("department not found")
+
+// ErrAmbiguousDepartment 同一父部门下存在多个同名子部门，无法按名称确定唯一部门
+var ErrAmbiguousDepartment = errors.New("department name is ambiguous")
+
+// ResolvePath 按名称路径查找部门并返回其ID，路径从根部门开始，空路径返回$root。
+// 找不到时返回 ErrDepartmentNotFound，遇到同名兄弟部门时返回 ErrAmbiguousDepartment。
+func (d *DepartmentService) ResolvePath(ctx context.Context, path string) (string, error) {
+	id := RootDepartmentID
+	for _, name := range SplitDepartmentPath(path) {
+		child, err := d.findChild(ctx, id, name)
+		if err != nil {
+			return "", fmt.Errorf("resolve %q: %w", path, err)
+		}
+		if child == nil {
+			return "", fmt.Errorf("resolve %q: %q: %w", path, name, ErrDepartmentNotFound)
+		}
+		id = child.ID
+	}
+	return id, nil
+}
+
+// EnsurePath 按名称路径查找部门，缺失的各级部门会依次创建，返回末级部门的ID。
+// 同一个 Client 上的并发调用对同一父部门下的同名部门只会创建一次；
+// 创建失败时会重新查询一次，以兼容其它进程已抢先创建的情况。
+func (d *DepartmentService) EnsurePath(ctx context.Context, path string) (string, error) {
+	id := RootDepartmentID
+	for _, name := range SplitDepartmentPath(path) {
+		childID, err := d.ensureChild(ctx, id, name)
+		if err != nil {
+			return "", fmt.Errorf("ensure %q: %w", path, err)
+		}
+		id = childID
+	}
+	return id, nil
+}
+
+func (d *DepartmentService) ensureChild(ctx context.Context, parentID, name string) (string, error) {
+	child, err := d.findChild(ctx, parentID, name)
+	if err != nil {
+		return "", err
+	}
+	if child != nil {
+		return child.ID, nil
+	}
+
+	mu := d.deptCreateLock(parentID, name)
+	mu.Lock()
+	defer mu.Unlock()
+
+	// 拿到锁后再查一次，其它调用方可能已经创建
+	if child, err = d.findChild(ctx, parentID, name); err != nil {
+		return "", err
+	}
+	if child != nil {
+		return child.ID, nil
+	}
+	created, createErr := d.Create(ctx, CreateDepartmentReq{BaseModifyReq{Name: name, ParentID: parentID}})
+	if createErr == nil {
+		return created.ID, nil
+	}
+	if child, err = d.findChild(ctx, parentID, name); err == nil && child != nil {
+		return child.ID, nil
+	}
+	return "", fmt.Errorf("create %q under %s: %w", name, parentID, createErr)
+}
+
+// findChild 查找父部门下名为 name 的直属子部门，不存在时返回 nil
+func (d *DepartmentService) findChild(ctx context.Context, parentID, name string) (*Department, error) {
+	var found *Department
+	for dept, err := range d.AllDepts(ctx, parentID) {
+		if err != nil {
+			return nil, fmt.Errorf("list children of %s: %w", parentID, err)
+		}
+		if strings.TrimSpace(dept.Name) != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%q under %s (%s, %s): %w", name, parentID, found.ID, dept.ID, ErrAmbiguousDepartment)
+		}
+		found = &dept
+	}
+	return found, nil
+}
+
+func (c *Client) deptCreateLock(parentID, name string) *sync.Mutex {
+	mu, _ := c.deptCreateLocks.LoadOrStore(parentID+"/"+name, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
@@ -0,0 +1,288 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// 部门邮件组地址与主管的同步
+
+// DepartmentEmail 按模板生成部门邮件组地址，支持的占位符：
+//
+//	{slug} 部门名称转成的小写字母、数字和 - 组成的串，名称中没有可用字符时使用部门ID
+//	{id}   部门ID
+//
+// 如 DepartmentEmail("dept-{slug}@example.com", dept)
+func DepartmentEmail(template string, dept Department) string {
+	slug := Slugify(dept.Name)
+	if slug == "" {
+		slug = Slugify(dept.ID)
+	}
+	return strings.NewReplacer("{slug}", slug, "{id}", dept.ID).Replace(template)
+}
+
+// Slugify 将名称转为只包含小写字母、数字和 - 的串，其它字符会被合并为一个 -
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// SyncDepartmentsReq 同步部门邮件组地址与主管的参数
+type SyncDepartmentsReq struct {
+	RootID string // 从该部门开始同步（含自身的子部门），为空时为$root，根部门本身不设置邮件组地址
+
+	// EmailTemplate 邮件组地址模板，见 DepartmentEmail；为空时不修改部门邮件组地址
+	EmailTemplate string
+	// KeepExistingEmail 为 true 时只给没有邮件组地址的部门设置地址
+	KeepExistingEmail bool
+
+	// Managers 部门主管，键为部门ID或名称路径（如 "研发中心/平台部"），值为主管邮箱；
+	// 未出现在其中的部门保留原有主管
+	Managers map[string][]string
+	// SkipUserManagers 为 true 时不修改成员的上级邮箱
+	SkipUserManagers bool
+
+	DryRun bool // 只输出变更，不做任何修改
+}
+
+// SyncChangeKind 同步中的变更类型
+type SyncChangeKind string
+
+const (
+	SyncDepartmentEmail    SyncChangeKind = "department-email"    // 部门邮件组地址
+	SyncDepartmentManagers SyncChangeKind = "department-managers" // 部门主管
+	SyncUserManager        SyncChangeKind = "user-manager"        // 用户上级邮箱
+)
+
+// SyncChange 一条变更
+type SyncChange struct {
+	Kind   SyncChangeKind
+	Target string // 部门ID或用户邮箱
+	Old    string
+	New    string
+	Err    error // 执行失败的原因，DryRun 时总为 nil
+}
+
+// SyncReport 同步结果
+type SyncReport struct {
+	DryRun  bool
+	Changes []SyncChange
+}
+
+// Failed 返回执行失败的变更
+func (r *SyncReport) Failed() []SyncChange {
+	var failed []SyncChange
+	for _, c := range r.Changes {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// String 输出可读的变更列表
+func (r *SyncReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d changes", len(r.Changes))
+	if r.DryRun {
+		b.WriteString(" (dry run)")
+	}
+	b.WriteByte('\n')
+	for _, c := range r.Changes {
+		fmt.Fprintf(&b, "  %-19s %s: %q -> %q", c.Kind, c.Target, c.Old, c.New)
+		if c.Err != nil {
+			fmt.Fprintf(&b, " (failed: %v)", c.Err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SyncDepartments 让部门邮件组地址、部门主管与成员的上级邮箱保持一致：
+// 按模板设置邮件组地址，按 Managers 设置部门主管，再把每个成员的上级邮箱设为其主部门
+// （DepartmentIds 中第一个位于同步范围内的部门）的第一位主管；部门未设置主管时沿上级部门查找，
+// 主管本人的上级取上级部门的主管。单条变更失败不影响其它变更，失败原因记录在报告中。
+func (d *DepartmentService) SyncDepartments(ctx context.Context, req SyncDepartmentsReq) (*SyncReport, error) {
+	if req.EmailTemplate != "" && !strings.Contains(req.EmailTemplate, "@") {
+		return nil, fmt.Errorf("invalid email template %q", req.EmailTemplate)
+	}
+	tree, err := d.Tree(ctx, req.RootID, WithTreeUsers())
+	if err != nil {
+		return nil, err
+	}
+
+	// 已知的用户 id 与邮箱
+	emailOf := map[string]string{}
+	idOf := map[string]string{}
+	remember := func(u User) {
+		emailOf[u.ID] = u.Email
+		idOf[strings.ToLower(u.Email)] = u.ID
+	}
+	tree.Walk(func(n *OrgNode) bool {
+		for _, u := range n.Users {
+			remember(u)
+		}
+		return true
+	})
+
+	report := &SyncReport{DryRun: req.DryRun}
+	record := func(c SyncChange, apply func() error) {
+		if !req.DryRun {
+			c.Err = apply()
+		}
+		report.Changes = append(report.Changes, c)
+	}
+
+	// 部门主管
+	keys := slices.Sorted(maps.Keys(req.Managers))
+	for _, key := range keys {
+		emails := req.Managers[key]
+		node := tree.Get(key)
+		if node == nil {
+			if node, err = tree.Lookup(key); err != nil {
+				return nil, fmt.Errorf("managers: %w", err)
+			}
+		}
+		ids := make([]string, 0, len(emails))
+		for _, email := range emails {
+			id, ok := idOf[strings.ToLower(email)]
+			if !ok {
+				u, err := d.User.Get(ctx, BaseUserReq{Email: email})
+				if err != nil {
+					return nil, fmt.Errorf("resolve manager %s: %w", email, err)
+				}
+				remember(*u)
+				id = u.ID
+			}
+			ids = append(ids, id)
+		}
+		if slices.Equal(ids, node.Department.Managers) {
+			continue
+		}
+		old := node.Department.Managers
+		node.Department.Managers = ids
+		record(SyncChange{
+			Kind:   SyncDepartmentManagers,
+			Target: node.ID,
+			Old:    strings.Join(old, ","),
+			New:    strings.Join(ids, ","),
+		}, func() error {
+			return d.Patch(ctx, PatchDepartmentReq{ID: node.ID, Managers: &ids})
+		})
+	}
+
+	// 部门邮件组地址
+	if req.EmailTemplate != "" {
+		used := map[string]string{}
+		tree.Walk(func(n *OrgNode) bool {
+			if n.Email != "" {
+				used[strings.ToLower(n.Email)] = n.ID
+			}
+			return true
+		})
+		tree.Walk(func(n *OrgNode) bool {
+			if n == tree.Root || n.Email != "" && req.KeepExistingEmail {
+				return true
+			}
+			email := DepartmentEmail(req.EmailTemplate, n.Department)
+			if strings.EqualFold(email, n.Email) {
+				return true
+			}
+			c := SyncChange{Kind: SyncDepartmentEmail, Target: n.ID, Old: n.Email, New: email}
+			if owner, ok := used[strings.ToLower(email)]; ok && owner != n.ID {
+				c.Err = fmt.Errorf("address already used by department %s", owner)
+				report.Changes = append(report.Changes, c)
+				return true
+			}
+			used[strings.ToLower(email)] = n.ID
+			record(c, func() error {
+				return d.Patch(ctx, PatchDepartmentReq{ID: n.ID, Email: &email})
+			})
+			return true
+		})
+	}
+
+	if req.SkipUserManagers {
+		return report, nil
+	}
+
+	// 主管中可能有不在同步范围内的用户，需要单独获取邮箱
+	var unknown []string
+	tree.Walk(func(n *OrgNode) bool {
+		for _, id := range n.Department.Managers {
+			if _, ok := emailOf[id]; !ok && !slices.Contains(unknown, id) {
+				unknown = append(unknown, id)
+			}
+		}
+		return true
+	})
+	if len(unknown) > 0 {
+		users, _, err := d.User.ListAllByIds(ctx, unknown)
+		if err != nil {
+			return report, fmt.Errorf("resolve managers: %w", err)
+		}
+		for _, u := range users {
+			remember(u)
+		}
+	}
+
+	// 成员的上级邮箱，每个用户只按主部门处理一次
+	done := map[string]bool{}
+	tree.Walk(func(n *OrgNode) bool {
+		for _, u := range n.Users {
+			if done[u.ID] {
+				continue
+			}
+			primary := n
+			for _, id := range u.DepartmentIds {
+				if p := tree.Get(id); p != nil {
+					primary = p
+					break
+				}
+			}
+			if primary != n && !slices.ContainsFunc(primary.Users, func(x User) bool { return x.ID == u.ID }) {
+				primary = n
+			}
+			done[u.ID] = true
+
+			manager := managerEmailFor(primary, u.ID, emailOf)
+			current := userManagerEmail(u)
+			if manager == "" || strings.EqualFold(manager, current) {
+				continue
+			}
+			record(SyncChange{Kind: SyncUserManager, Target: u.Email, Old: current, New: manager}, func() error {
+				_, err := d.User.Patch(ctx, PatchUserReq{BaseUserReq: BaseUserReq{ID: u.ID}, ManagerEmail: &manager})
+				return err
+			})
+		}
+		return true
+	})
+	return report, nil
+}
+
+// managerEmailFor 沿部门向上查找第一位不是用户本人且邮箱已知的主管
+func managerEmailFor(n *OrgNode, userID string, emailOf map[string]string) string {
+	for cur := n; cur != nil; cur = cur.Parent {
+		for _, id := range cur.Department.Managers {
+			if id != userID && emailOf[id] != "" {
+				return emailOf[id]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package alimail
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncDirectory 有状态的假服务：$root 下有 研发(A)/平台(A1)、产品(B)，修改会保存下来，
+// 与真实接口一样，用户的上级只通过 managerInfo 返回
+func syncDirectory() fakeResponder {
+	var mu sync.Mutex
+	depts := map[string]*Department{
+		RootDepartmentID: {ID: RootDepartmentID, Name: "root"},
+		"A":              {ID: "A", Name: "研发", ParentID: RootDepartmentID, Managers: []string{"m1"}},
+		"A1":             {ID: "A1", Name: "平台", ParentID: "A"},
+		"B":              {ID: "B", Name: "产品", ParentID: RootDepartmentID, Managers: []string{"m2"}},
+	}
+	users := map[string]*User{
+		"m1": {ID: "m1", Email: "m1@example.com", DepartmentIds: []string{"A"}},
+		"u1": {ID: "u1", Email: "u1@example.com", DepartmentIds: []string{"A"}},
+		"u2": {ID: "u2", Email: "u2@example.com", DepartmentIds: []string{"A1"}},
+		"m2": {ID: "m2", Email: "m2@example.com", DepartmentIds: []string{"X"}},
+	}
+	return func(req recordedRequest) (int, any) {
+		mu.Lock()
+		defer mu.Unlock()
+		first := req.Query.Get("offset") == "0"
+		parts := strings.Split(strings.TrimPrefix(req.Path, "/v2/"), "/")
+		switch {
+		case req.Method == MethodPatch && parts[0] == "departments":
+			d := depts[parts[1]]
+			if v, ok := req.Body["email"].(string); ok {
+				d.Email = v
+			}
+			if v, ok := req.Body["managers"].([]any); ok {
+				d.Managers = nil
+				for _, id := range v {
+					d.Managers = append(d.Managers, id.(string))
+				}
+			}
+			return http.StatusOK, *d
+		case req.Method == MethodPatch && parts[0] == "users":
+			u := users[parts[1]]
+			u.ManagerInfo.Email = req.Body["managerEmail"].(string)
+			return http.StatusOK, *u
+		case req.Method != MethodGet:
+			return 0, nil
+		case req.Path == "/v2/users/listByIds":
+			rsp := listUserByIdsResponse{Users: []User{}}
+			for _, id := range req.Body["ids"].([]any) {
+				if u, ok := users[id.(string)]; ok {
+					rsp.Users = append(rsp.Users, *u)
+				}
+			}
+			return http.StatusOK, rsp
+		case len(parts) == 2 && parts[0] == "departments":
+			return http.StatusOK, *depts[parts[1]]
+		case len(parts) == 3 && parts[2] == "departments":
+			rsp := ListDepartmentDeptsRsp{Departments: []Department{}}
+			for _, d := range depts {
+				if d.ParentID == parts[1] {
+					rsp.Total++
+					if first {
+						rsp.Departments = append(rsp.Departments, *d)
+					}
+				}
+			}
+			return http.StatusOK, rsp
+		case len(parts) == 3 && parts[2] == "users":
+			rsp := ListDepartmentUsersRsp{Users: []User{}}
+			for _, id := range []string{"m1", "u1", "u2", "m2"} {
+				if u := users[id]; u.DepartmentIds[0] == parts[1] {
+					rsp.Total++
+					if first {
+						rsp.Users = append(rsp.Users, *u)
+					}
+				}
+			}
+			return http.StatusOK, rsp
+		}
+		return 0, nil
+	}
+}
+
+func TestSyncDepartmentsConverges(t *testing.T) {
+	c, requests := newFakeServer(t, syncDirectory())
+	ctx := context.Background()
+	req := SyncDepartmentsReq{
+		EmailTemplate: "dept-{slug}@example.com",
+		Managers:      map[string][]string{"研发/平台": {"u1@example.com"}},
+	}
+
+	report, err := c.Department.SyncDepartments(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, ch := range report.Changes {
+		if ch.Err != nil {
+			t.Errorf("%s %s: %v", ch.Kind, ch.Target, ch.Err)
+		}
+		got[string(ch.Kind)+" "+ch.Target] = ch.New
+	}
+	want := map[string]string{
+		"department-managers A1":      "u1",
+		"department-email A":          "dept-a@example.com",
+		"department-email A1":         "dept-a1@example.com",
+		"department-email B":          "dept-b@example.com",
+		"user-manager u1@example.com": "m1@example.com",
+		"user-manager u2@example.com": "u1@example.com",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if w := writes(requests()); len(w) != len(want) {
+		t.Errorf("sent %d writes, want %d", len(w), len(want))
+	}
+
+	// 状态未变时第二次同步没有任何变更
+	before := len(writes(requests()))
+	report, err = c.Department.SyncDepartments(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 0 {
+		t.Errorf("second run:\n%s", report)
+	}
+	if w := writes(requests()); len(w) != before {
+		t.Errorf("second run sent %d writes", len(w)-before)
+	}
+}
@@ -0,0 +1,348 @@
+package alimail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 目录即代码：在 YAML/JSON 文件中声明部门、用户、别名、邮件组和公共联系人，
+// 与线上状态对比生成变更计划，再按依赖顺序执行。
+//
+// 只有文件中出现的资源类型会被管理：比如文件中没有 groups，就不会对邮件组做任何修改，
+// 即使开启了 Prune 也不会删除邮件组。字段为空表示不管理该字段，保留线上的值。
+
+// DirectoryService 目录即代码服务
+type DirectoryService struct{ *Client }
+
+// DirectorySpec 期望的目录状态
+type DirectorySpec struct {
+	Departments    []DepartmentSpec    `json:"departments,omitempty" yaml:"departments,omitempty"`
+	Users          []UserSpec          `json:"users,omitempty" yaml:"users,omitempty"`
+	Groups         []GroupSpec         `json:"groups,omitempty" yaml:"groups,omitempty"`
+	SharedContacts []SharedContactSpec `json:"sharedContacts,omitempty" yaml:"sharedContacts,omitempty"`
+	// Protected 受保护的资源地址，这些资源不会被创建、修改或删除；支持 path.Match 通配符，
+	// 地址同时保护其下级：部门与联系人分组保护下级部门、分组和联系人，用户保护其别名，邮件组保护其成员，
+	// 如 "user:ceo@example.com"、"department:高管"、"department:*/财务"
+	Protected []string `json:"protected,omitempty" yaml:"protected,omitempty"`
+}
+
+// DepartmentSpec 部门
+type DepartmentSpec struct {
+	Path     string   `json:"path" yaml:"path"`                             // 名称路径，如 "研发中心/平台部"
+	Email    string   `json:"email,omitempty" yaml:"email,omitempty"`       // 邮件组地址
+	Managers []string `json:"managers,omitempty" yaml:"managers,omitempty"` // 主管邮箱
+	Hidden   *bool    `json:"hidden,omitempty" yaml:"hidden,omitempty"`     // 是否隐藏
+}
+
+// UserSpec 用户，Aliases 为 nil 时不管理别名
+type UserSpec struct {
+	Email       string   `json:"email" yaml:"email"`
+	Name        string   `json:"name" yaml:"name"`
+	Departments []string `json:"departments,omitempty" yaml:"departments,omitempty"` // 部门名称路径
+	JobTitle    string   `json:"jobTitle,omitempty" yaml:"jobTitle,omitempty"`
+	EmployeeNo  string   `json:"employeeNo,omitempty" yaml:"employeeNo,omitempty"`
+	Phone       string   `json:"phone,omitempty" yaml:"phone,omitempty"`
+	WorkPhone   string   `json:"workPhone,omitempty" yaml:"workPhone,omitempty"`
+	Manager     string   `json:"manager,omitempty" yaml:"manager,omitempty"` // 上级邮箱
+	Hidden      *bool    `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	Aliases     []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+// GroupSpec 邮件组，Members 为 nil 时不管理成员
+type GroupSpec struct {
+	Email   string   `json:"email" yaml:"email"`
+	Name    string   `json:"name" yaml:"name"`
+	Members []string `json:"members,omitempty" yaml:"members,omitempty"`
+	Hidden  *bool    `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+}
+
+// SharedContactSpec 公共联系人，同一分组下按邮箱（没有邮箱时按姓名）识别
+type SharedContactSpec struct {
+	Folder      string `json:"folder" yaml:"folder"` // 分组名称路径
+	Name        string `json:"name" yaml:"name"`
+	Email       string `json:"email,omitempty" yaml:"email,omitempty"`
+	Phone       string `json:"phone,omitempty" yaml:"phone,omitempty"`
+	WorkPhone   string `json:"workPhone,omitempty" yaml:"workPhone,omitempty"`
+	CompanyName string `json:"companyName,omitempty" yaml:"companyName,omitempty"`
+	JobTitle    string `json:"jobTitle,omitempty" yaml:"jobTitle,omitempty"`
+}
+
+// LoadDirectorySpec 从文件读取期望状态，支持 YAML 与 JSON
+func LoadDirectorySpec(name string) (*DirectorySpec, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseDirectorySpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return spec, nil
+}
+
+// ParseDirectorySpec 解析 YAML 或 JSON 格式的期望状态，未知字段会报错，并检查重复的资源
+func ParseDirectorySpec(data []byte) (*DirectorySpec, error) {
+	var spec DirectorySpec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("failed to parse directory spec: %w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Validate 检查必填字段与重复的资源
+func (s *DirectorySpec) Validate() error {
+	seen := map[string]bool{}
+	dup := func(addr string) error {
+		if seen[addr] {
+			return fmt.Errorf("duplicate %s", addr)
+		}
+		seen[addr] = true
+		return nil
+	}
+	for _, d := range s.Departments {
+		if len(SplitDepartmentPath(d.Path)) == 0 {
+			return fmt.Errorf("department path is required")
+		}
+		if err := dup(deptAddress(d.Path)); err != nil {
+			return err
+		}
+	}
+	for _, u := range s.Users {
+		if u.Email == "" || u.Name == "" {
+			return fmt.Errorf("user email and name are required")
+		}
+		if err := dup(userAddress(u.Email)); err != nil {
+			return err
+		}
+	}
+	for _, g := range s.Groups {
+		if g.Email == "" || g.Name == "" {
+			return fmt.Errorf("group email and name are required")
+		}
+		if err := dup(groupAddress(g.Email)); err != nil {
+			return err
+		}
+	}
+	for _, c := range s.SharedContacts {
+		if len(SplitDepartmentPath(c.Folder)) == 0 || c.Name == "" {
+			return fmt.Errorf("shared contact folder and name are required")
+		}
+		if err := dup(contactAddress(c.Folder, c.Email, c.Name)); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.Protected {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid protected pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// 资源地址
+func deptAddress(p string) string {
+	return "department:" + strings.Join(SplitDepartmentPath(p), "/")
+}
+func userAddress(email string) string  { return "user:" + strings.ToLower(email) }
+func groupAddress(email string) string { return "group:" + strings.ToLower(email) }
+func aliasAddress(email, alias string) string {
+	return "alias:" + strings.ToLower(email) + "/" + strings.ToLower(alias)
+}
+func memberAddress(group, member string) string {
+	return "group-member:" + strings.ToLower(group) + "/" + strings.ToLower(member)
+}
+func folderAddress(p string) string {
+	return "contact-folder:" + strings.Join(SplitDepartmentPath(p), "/")
+}
+func contactAddress(folder, email, name string) string {
+	key := strings.ToLower(email)
+	if key == "" {
+		key = name
+	}
+	return "contact:" + strings.Join(SplitDepartmentPath(folder), "/") + "/" + key
+}
+
+// ChangeAction 变更动作
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// FieldDiff 字段的变化
+type FieldDiff struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new"`
+}
+
+// DirectoryChange 计划中的一项变更
+type DirectoryChange struct {
+	Action    ChangeAction
+	Address   string // 资源地址，如 "user:a@example.com"
+	Diffs     []FieldDiff
+	Protected bool  // 受保护的变更，执行时跳过
+	Done      bool  // 已执行成功
+	Err       error // 执行失败的原因
+
+	phase int // 执行阶段，保证依赖顺序
+	depth int // 同一阶段内的排序，部门与分组按深度
+	apply func(ctx context.Context, st *applyState) error
+}
+
+// DirectoryPlan 变更计划，由 DirectoryService.Plan 生成
+type DirectoryPlan struct {
+	Changes []*DirectoryChange
+
+	client *Client
+	state  *applyState
+}
+
+// PlanOptions 生成计划的选项
+type PlanOptions struct {
+	Prune     bool     // 删除线上存在但文件中没有的资源，以及多余的别名和邮件组成员
+	Protected []string // 额外的受保护资源地址，与文件中的 protected 合并
+}
+
+// Counts 返回创建、更新、删除的数量，受保护而跳过的变更不计入
+func (p *DirectoryPlan) Counts() (create, update, del int) {
+	for _, c := range p.Changes {
+		switch {
+		case c.Protected:
+		case c.Action == ChangeCreate:
+			create++
+		case c.Action == ChangeUpdate:
+			update++
+		default:
+			del++
+		}
+	}
+	return
+}
+
+// Empty 计划中是否没有需要执行的变更
+func (p *DirectoryPlan) Empty() bool {
+	c, u, d := p.Counts()
+	return c+u+d == 0
+}
+
+// String 以类似 terraform plan 的格式输出计划
+func (p *DirectoryPlan) String() string {
+	var b strings.Builder
+	for _, c := range p.Changes {
+		sign := map[ChangeAction]string{ChangeCreate: "+", ChangeUpdate: "~", ChangeDelete: "-"}[c.Action]
+		fmt.Fprintf(&b, "  %s %s", sign, c.Address)
+		switch {
+		case c.Protected:
+			b.WriteString(" (protected, skipped)")
+		case c.Err != nil:
+			fmt.Fprintf(&b, " (failed: %v)", c.Err)
+		case c.Done:
+			b.WriteString(" (done)")
+		}
+		b.WriteByte('\n')
+		for _, d := range c.Diffs {
+			if c.Action == ChangeCreate {
+				fmt.Fprintf(&b, "      %s: %q\n", d.Field, d.New)
+			} else {
+				fmt.Fprintf(&b, "      %s: %q -> %q\n", d.Field, d.Old, d.New)
+			}
+		}
+	}
+	create, update, del := p.Counts()
+	if create+update+del == 0 {
+		b.WriteString("No changes. Directory matches the spec.\n")
+	} else {
+		fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete.\n", create, update, del)
+	}
+	return b.String()
+}
+
+// ApplyResult 执行结果
+type ApplyResult struct {
+	Applied   int               // 成功执行的变更数
+	Skipped   int               // 受保护而跳过的变更数
+	Passwords map[string]string // 新建用户的初始密码，按邮箱，用户首次登录时需要修改
+}
+
+// Apply 按依赖顺序执行计划：先创建部门、用户、别名、邮件组与联系人，再更新，最后按相反顺序删除。
+// 遇到错误立即停止，已执行的变更在计划中标记为 Done，失败的变更记录 Err。
+func (p *DirectoryPlan) Apply(ctx context.Context) (*ApplyResult, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("plan was not created by DirectoryService.Plan")
+	}
+	rst := &ApplyResult{Passwords: p.state.passwords}
+	for _, c := range p.Changes {
+		if c.Done {
+			continue
+		}
+		if c.Protected {
+			rst.Skipped++
+			continue
+		}
+		if err := c.apply(ctx, p.state); err != nil {
+			c.Err = err
+			return rst, fmt.Errorf("%s %s: %w", c.Action, c.Address, err)
+		}
+		c.Done = true
+		rst.Applied++
+	}
+	return rst, nil
+}
+
+// parentKinds 资源地址的上级资源类型，上级受保护时下级同样受保护
+var parentKinds = map[string]string{
+	"department":     "department",
+	"contact-folder": "contact-folder",
+	"contact":        "contact-folder",
+	"alias":          "user",
+	"group-member":   "group",
+}
+
+// protectedBy 判断资源地址是否受保护。除地址本身外，还会用每一级上级资源的地址匹配，
+// 因此带通配符的模式同样保护下级，如 "department:高管*" 保护 "department:高管层/秘书处"
+func protectedBy(patterns []string, addr string) bool {
+	scopes := []string{addr}
+	kind, rest, _ := strings.Cut(addr, ":")
+	if parent, ok := parentKinds[kind]; ok {
+		segs := strings.Split(rest, "/")
+		for i := 1; i < len(segs); i++ {
+			scopes = append(scopes, parent+":"+strings.Join(segs[:i], "/"))
+		}
+	}
+	for _, p := range patterns {
+		for _, s := range scopes {
+			if ok, _ := path.Match(p, s); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortChanges 按阶段排序；创建按深度从浅到深，删除按深度从深到浅
+func sortChanges(changes []*DirectoryChange) {
+	slices.SortStableFunc(changes, func(a, b *DirectoryChange) int {
+		if a.phase != b.phase {
+			return a.phase - b.phase
+		}
+		if a.depth != b.depth {
+			return a.depth - b.depth
+		}
+		return strings.Compare(a.Address, b.Address)
+	})
+}
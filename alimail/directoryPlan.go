@@ -0,0 +1,908 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 执行阶段，数字越小越先执行
+const (
+	phaseDeptCreate = iota
+	phaseUserCreate
+	phaseUserUpdate
+	phaseDeptUpdate
+	phaseAliasCreate
+	phaseGroupCreate
+	phaseGroupUpdate
+	phaseMemberAdd
+	phaseFolderCreate
+	phaseContactCreate
+	phaseContactUpdate
+	phaseContactDelete
+	phaseFolderDelete
+	phaseMemberRemove
+	phaseGroupDelete
+	phaseAliasDelete
+	phaseUserDelete
+	phaseDeptDelete
+)
+
+// applyState 执行计划时解析名称路径和邮箱对应的 id，包括执行过程中新建的资源
+type applyState struct {
+	c         *Client
+	deptIDs   map[string]string // 部门名称路径 -> id，根部门为 ""
+	folderIDs map[string]string // 联系人分组名称路径 -> id，根分组为 ""
+	userIDs   map[string]string // 小写邮箱 -> 用户 id
+	groupIDs  map[string]string // 小写邮箱 -> 邮件组 id
+	passwords map[string]string // 新建用户的初始密码
+}
+
+func (st *applyState) deptID(ctx context.Context, p string) (string, error) {
+	key := strings.Join(SplitDepartmentPath(p), "/")
+	if id, ok := st.deptIDs[key]; ok {
+		return id, nil
+	}
+	id, err := st.c.Department.ResolvePath(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	st.deptIDs[key] = id
+	return id, nil
+}
+
+func (st *applyState) deptIDList(ctx context.Context, paths []string) ([]string, error) {
+	ids := make([]string, 0, len(paths))
+	for _, p := range paths {
+		id, err := st.deptID(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (st *applyState) userID(ctx context.Context, email string) (string, error) {
+	key := strings.ToLower(email)
+	if id, ok := st.userIDs[key]; ok {
+		return id, nil
+	}
+	u, err := st.c.User.Get(ctx, BaseUserReq{Email: email})
+	if err != nil {
+		return "", fmt.Errorf("resolve user %s: %w", email, err)
+	}
+	st.userIDs[key] = u.ID
+	return u.ID, nil
+}
+
+func (st *applyState) folderID(p string) (string, error) {
+	key := strings.Join(SplitDepartmentPath(p), "/")
+	if id, ok := st.folderIDs[key]; ok {
+		return id, nil
+	}
+	return "", fmt.Errorf("shared contact folder %q not found", key)
+}
+
+// parentPath 返回名称路径的上一级，一级路径返回 ""
+func parentPath(p string) string {
+	segs := SplitDepartmentPath(p)
+	if len(segs) <= 1 {
+		return ""
+	}
+	return strings.Join(segs[:len(segs)-1], "/")
+}
+
+// withAncestors 返回路径及其所有上级路径
+func withAncestors(p string) []string {
+	segs := SplitDepartmentPath(p)
+	list := make([]string, 0, len(segs))
+	for i := range segs {
+		list = append(list, strings.Join(segs[:i+1], "/"))
+	}
+	return list
+}
+
+func lowerSorted(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		out = append(out, strings.ToLower(strings.TrimSpace(s)))
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+func diffString(diffs []FieldDiff, field, old, new string) []FieldDiff {
+	if new != "" && old != new {
+		diffs = append(diffs, FieldDiff{Field: field, Old: old, New: new})
+	}
+	return diffs
+}
+
+func diffBool(diffs []FieldDiff, field string, old bool, new *bool) []FieldDiff {
+	if new != nil && old != *new {
+		diffs = append(diffs, FieldDiff{Field: field, Old: strconv.FormatBool(old), New: strconv.FormatBool(*new)})
+	}
+	return diffs
+}
+
+func diffList(diffs []FieldDiff, field string, old, new []string) []FieldDiff {
+	if new != nil && !slices.Equal(old, new) {
+		diffs = append(diffs, FieldDiff{Field: field, Old: strings.Join(old, ","), New: strings.Join(new, ",")})
+	}
+	return diffs
+}
+
+// directoryPlanner 生成计划时的上下文
+type directoryPlanner struct {
+	s         *DirectoryService
+	spec      DirectorySpec
+	opts      PlanOptions
+	protected []string
+	plan      *DirectoryPlan
+	st        *applyState
+
+	tree      *OrgTree
+	deptNodes map[string]*OrgNode // 名称路径 -> 线上部门
+	users     map[string]User     // 小写邮箱 -> 线上用户
+	emailOf   map[string]string   // 用户 id -> 邮箱
+}
+
+func (p *directoryPlanner) add(c *DirectoryChange) {
+	c.Protected = protectedBy(p.protected, c.Address)
+	p.plan.Changes = append(p.plan.Changes, c)
+}
+
+// Plan 对比期望状态与线上状态，生成变更计划，不做任何修改
+func (s *DirectoryService) Plan(ctx context.Context, spec DirectorySpec, opts PlanOptions) (*DirectoryPlan, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	st := &applyState{
+		c:         s.Client,
+		deptIDs:   map[string]string{"": RootDepartmentID},
+		folderIDs: map[string]string{"": RootSharedContactFolderID},
+		userIDs:   map[string]string{},
+		groupIDs:  map[string]string{},
+		passwords: map[string]string{},
+	}
+	p := &directoryPlanner{
+		s:         s,
+		spec:      spec,
+		opts:      opts,
+		protected: append(slices.Clone(spec.Protected), opts.Protected...),
+		plan:      &DirectoryPlan{client: s.Client, state: st},
+		st:        st,
+	}
+	if spec.Departments != nil || spec.Users != nil {
+		if err := p.loadDirectory(ctx); err != nil {
+			return nil, err
+		}
+		p.planDepartments()
+		p.planUsers()
+	}
+	if spec.Groups != nil {
+		if err := p.planGroups(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if spec.SharedContacts != nil {
+		if err := p.planSharedContacts(ctx); err != nil {
+			return nil, err
+		}
+	}
+	sortChanges(p.plan.Changes)
+	return p.plan, nil
+}
+
+// loadDirectory 获取线上的部门树与用户
+func (p *directoryPlanner) loadDirectory(ctx context.Context) error {
+	tree, err := p.s.Department.Tree(ctx, "", WithTreeUsers())
+	if err != nil {
+		return err
+	}
+	p.tree = tree
+	p.deptNodes = map[string]*OrgNode{}
+	p.users = map[string]User{}
+	p.emailOf = map[string]string{}
+	var dupErr error
+	tree.Walk(func(n *OrgNode) bool {
+		key := strings.Join(n.Path(), "/")
+		if other, ok := p.deptNodes[key]; ok && dupErr == nil {
+			dupErr = fmt.Errorf("department path %q is ambiguous (%s, %s): %w", key, other.ID, n.ID, ErrAmbiguousDepartment)
+		}
+		p.deptNodes[key] = n
+		p.st.deptIDs[key] = n.ID
+		for _, u := range n.Users {
+			email := strings.ToLower(u.Email)
+			u.DepartmentIds = mergeDepartmentIds(p.users[email].DepartmentIds, u, n.ID)
+			p.users[email] = u
+			p.emailOf[u.ID] = u.Email
+			p.st.userIDs[email] = u.ID
+		}
+		return true
+	})
+	if dupErr != nil {
+		return dupErr
+	}
+	// 部门主管可能不在任何部门中
+	var unknown []string
+	for _, n := range p.deptNodes {
+		for _, id := range n.Department.Managers {
+			if _, ok := p.emailOf[id]; !ok && !slices.Contains(unknown, id) {
+				unknown = append(unknown, id)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		users, _, err := p.s.User.ListAllByIds(ctx, unknown)
+		if err != nil {
+			return fmt.Errorf("resolve department managers: %w", err)
+		}
+		for _, u := range users {
+			p.emailOf[u.ID] = u.Email
+		}
+	}
+	return nil
+}
+
+func (p *directoryPlanner) planDepartments() {
+	declared := map[string]DepartmentSpec{}
+	for _, d := range p.spec.Departments {
+		declared[strings.Join(SplitDepartmentPath(d.Path), "/")] = d
+	}
+	// 需要存在的部门：声明的部门、用户引用的部门，以及它们的上级
+	wanted := map[string]bool{}
+	for key := range declared {
+		for _, a := range withAncestors(key) {
+			wanted[a] = true
+		}
+	}
+	for _, u := range p.spec.Users {
+		for _, d := range u.Departments {
+			for _, a := range withAncestors(d) {
+				wanted[a] = true
+			}
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(wanted)) {
+		spec, isDeclared := declared[key]
+		managers := lowerSorted(spec.Managers)
+		if spec.Managers == nil {
+			managers = nil
+		}
+		node := p.deptNodes[key]
+		depth := len(SplitDepartmentPath(key))
+		if node == nil {
+			var diffs []FieldDiff
+			diffs = diffString(diffs, "email", "", spec.Email)
+			diffs = diffBool(diffs, "hidden", false, spec.Hidden)
+			p.add(&DirectoryChange{
+				Action:  ChangeCreate,
+				Address: deptAddress(key),
+				Diffs:   diffs,
+				phase:   phaseDeptCreate,
+				depth:   depth,
+				apply: func(ctx context.Context, st *applyState) error {
+					parentID, err := st.deptID(ctx, parentPath(key))
+					if err != nil {
+						return err
+					}
+					req := BaseModifyReq{Name: SplitDepartmentPath(key)[depth-1], ParentID: parentID, Email: spec.Email}
+					if spec.Hidden != nil {
+						req.IsHidden = *spec.Hidden
+					}
+					dept, err := st.c.Department.Create(ctx, CreateDepartmentReq{req})
+					if err != nil {
+						return err
+					}
+					st.deptIDs[key] = dept.ID
+					return nil
+				},
+			})
+			if len(managers) > 0 {
+				p.add(p.deptUpdate(key, []FieldDiff{{Field: "managers", New: strings.Join(managers, ",")}}, PatchDepartmentReq{}, managers))
+			}
+			continue
+		}
+		if !isDeclared {
+			continue
+		}
+		var (
+			diffs []FieldDiff
+			patch PatchDepartmentReq
+		)
+		if spec.Email != "" && !strings.EqualFold(spec.Email, node.Email) {
+			diffs = diffString(diffs, "email", node.Email, spec.Email)
+			patch.Email = Ptr(spec.Email)
+		}
+		if d := diffBool(nil, "hidden", node.IsHidden, spec.Hidden); len(d) > 0 {
+			diffs = append(diffs, d...)
+			patch.IsHidden = spec.Hidden
+		}
+		live := make([]string, 0, len(node.Department.Managers))
+		for _, id := range node.Department.Managers {
+			email := p.emailOf[id]
+			if email == "" {
+				email = id
+			}
+			live = append(live, email)
+		}
+		live = lowerSorted(live)
+		if d := diffList(nil, "managers", live, managers); len(d) > 0 {
+			diffs = append(diffs, d...)
+		} else {
+			managers = nil
+		}
+		if len(diffs) > 0 {
+			p.add(p.deptUpdate(key, diffs, patch, managers))
+		}
+	}
+
+	if p.spec.Departments == nil || !p.opts.Prune {
+		return
+	}
+	for key, node := range p.deptNodes {
+		if node == p.tree.Root || wanted[key] {
+			continue
+		}
+		id := node.ID
+		p.add(&DirectoryChange{
+			Action:  ChangeDelete,
+			Address: deptAddress(key),
+			phase:   phaseDeptDelete,
+			depth:   -node.Depth,
+			apply: func(ctx context.Context, st *applyState) error {
+				return st.c.Department.Delete(ctx, id)
+			},
+		})
+	}
+}
+
+// deptUpdate 更新部门，managers 不为 nil 时在执行时解析为用户 id
+func (p *directoryPlanner) deptUpdate(key string, diffs []FieldDiff, patch PatchDepartmentReq, managers []string) *DirectoryChange {
+	return &DirectoryChange{
+		Action:  ChangeUpdate,
+		Address: deptAddress(key),
+		Diffs:   diffs,
+		phase:   phaseDeptUpdate,
+		apply: func(ctx context.Context, st *applyState) error {
+			id, err := st.deptID(ctx, key)
+			if err != nil {
+				return err
+			}
+			patch.ID = id
+			if managers != nil {
+				ids := make([]string, 0, len(managers))
+				for _, email := range managers {
+					uid, err := st.userID(ctx, email)
+					if err != nil {
+						return err
+					}
+					ids = append(ids, uid)
+				}
+				patch.Managers = &ids
+			}
+			return st.c.Department.Patch(ctx, patch)
+		},
+	}
+}
+
+// userDeptPaths 返回线上用户所在部门的名称路径
+func (p *directoryPlanner) userDeptPaths(u User) []string {
+	paths := make([]string, 0, len(u.DepartmentIds))
+	for _, id := range u.DepartmentIds {
+		if n := p.tree.Get(id); n != nil {
+			paths = append(paths, strings.Join(n.Path(), "/"))
+		} else {
+			paths = append(paths, id)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func (p *directoryPlanner) planUsers() {
+	if p.spec.Users == nil {
+		return
+	}
+	declared := map[string]bool{}
+	for _, spec := range p.spec.Users {
+		key := strings.ToLower(spec.Email)
+		declared[key] = true
+		var depts []string
+		if spec.Departments != nil {
+			depts = make([]string, 0, len(spec.Departments))
+			for _, d := range spec.Departments {
+				depts = append(depts, strings.Join(SplitDepartmentPath(d), "/"))
+			}
+			slices.Sort(depts)
+		}
+		live, exists := p.users[key]
+
+		if !exists {
+			var diffs []FieldDiff
+			diffs = diffString(diffs, "name", "", spec.Name)
+			diffs = diffList(diffs, "departments", nil, depts)
+			diffs = diffString(diffs, "jobTitle", "", spec.JobTitle)
+			diffs = diffString(diffs, "employeeNo", "", spec.EmployeeNo)
+			diffs = diffString(diffs, "phone", "", spec.Phone)
+			diffs = diffString(diffs, "workPhone", "", spec.WorkPhone)
+			diffs = diffBool(diffs, "hidden", false, spec.Hidden)
+			p.add(&DirectoryChange{
+				Action:  ChangeCreate,
+				Address: userAddress(spec.Email),
+				Diffs:   diffs,
+				phase:   phaseUserCreate,
+				apply: func(ctx context.Context, st *applyState) error {
+					ids, err := st.deptIDList(ctx, depts)
+					if err != nil {
+						return err
+					}
+					if len(ids) == 0 {
+						ids = []string{RootDepartmentID}
+					}
+					password, err := st.c.PasswordPolicy().Generate(PasswordContext{Name: spec.Name, Email: spec.Email})
+					if err != nil {
+						return err
+					}
+					req := CreateUserReq{
+						Email:                         spec.Email,
+						Password:                      password,
+						Name:                          spec.Name,
+						JobTitle:                      spec.JobTitle,
+						EmployeeNo:                    spec.EmployeeNo,
+						Phone:                         spec.Phone,
+						WorkPhone:                     spec.WorkPhone,
+						DepartmentIds:                 ids,
+						ForceChangePasswordNextSignIn: true,
+					}
+					if spec.Hidden != nil {
+						req.IsHidden = *spec.Hidden
+					}
+					user, err := st.c.User.Create(ctx, req)
+					if err != nil {
+						return err
+					}
+					st.userIDs[key] = user.ID
+					st.passwords[spec.Email] = password
+					return nil
+				},
+			})
+			// 上级可能也是本次新建的用户，等全部用户创建后再设置
+			if spec.Manager != "" {
+				p.add(userPatch(spec.Email, []FieldDiff{{Field: "manager", New: spec.Manager}}, PatchUserReq{ManagerEmail: Ptr(spec.Manager)}, nil))
+			}
+			for _, alias := range lowerSorted(spec.Aliases) {
+				p.add(aliasChange(ChangeCreate, spec.Email, alias))
+			}
+			continue
+		}
+
+		var (
+			diffs []FieldDiff
+			patch PatchUserReq
+		)
+		str := func(field, old, new string, dst **string) {
+			if d := diffString(nil, field, old, new); len(d) > 0 {
+				diffs = append(diffs, d...)
+				*dst = Ptr(new)
+			}
+		}
+		str("name", live.Name, spec.Name, &patch.Name)
+		str("jobTitle", live.JobTitle, spec.JobTitle, &patch.JobTitle)
+		str("employeeNo", live.EmployeeNo, spec.EmployeeNo, &patch.EmployeeNo)
+		str("phone", live.Phone, spec.Phone, &patch.Phone)
+		str("workPhone", live.WorkPhone, spec.WorkPhone, &patch.WorkPhone)
+		if manager := userManagerEmail(live); spec.Manager != "" && !strings.EqualFold(manager, spec.Manager) {
+			diffs = diffString(diffs, "manager", manager, spec.Manager)
+			patch.ManagerEmail = Ptr(spec.Manager)
+		}
+		if d := diffBool(nil, "hidden", live.IsHidden, spec.Hidden); len(d) > 0 {
+			diffs = append(diffs, d...)
+			patch.IsHidden = spec.Hidden
+		}
+		var newDepts []string
+		if d := diffList(nil, "departments", p.userDeptPaths(live), depts); len(d) > 0 {
+			diffs = append(diffs, d...)
+			newDepts = depts
+		}
+		if len(diffs) > 0 {
+			p.add(userPatch(spec.Email, diffs, patch, newDepts))
+		}
+
+		if spec.Aliases != nil {
+			want, have := lowerSorted(spec.Aliases), lowerSorted(live.EmailAliases)
+			for _, alias := range want {
+				if !slices.Contains(have, alias) {
+					p.add(aliasChange(ChangeCreate, spec.Email, alias))
+				}
+			}
+			if p.opts.Prune {
+				for _, alias := range have {
+					if !slices.Contains(want, alias) {
+						p.add(aliasChange(ChangeDelete, spec.Email, alias))
+					}
+				}
+			}
+		}
+	}
+
+	if !p.opts.Prune {
+		return
+	}
+	for key, u := range p.users {
+		if declared[key] {
+			continue
+		}
+		email := u.Email
+		p.add(&DirectoryChange{
+			Action:  ChangeDelete,
+			Address: userAddress(email),
+			phase:   phaseUserDelete,
+			apply: func(ctx context.Context, st *applyState) error {
+				return st.c.User.Delete(ctx, BaseUserReq{Email: email})
+			},
+		})
+	}
+}
+
+// userPatch 部分更新用户，depts 不为 nil 时在执行时解析为部门 id
+func userPatch(email string, diffs []FieldDiff, patch PatchUserReq, depts []string) *DirectoryChange {
+	return &DirectoryChange{
+		Action:  ChangeUpdate,
+		Address: userAddress(email),
+		Diffs:   diffs,
+		phase:   phaseUserUpdate,
+		apply: func(ctx context.Context, st *applyState) error {
+			patch.BaseUserReq = BaseUserReq{Email: email}
+			if depts != nil {
+				ids, err := st.deptIDList(ctx, depts)
+				if err != nil {
+					return err
+				}
+				patch.DepartmentIds = &ids
+			}
+			_, err := st.c.User.Patch(ctx, patch)
+			return err
+		},
+	}
+}
+
+func aliasChange(action ChangeAction, email, alias string) *DirectoryChange {
+	c := &DirectoryChange{Action: action, Address: aliasAddress(email, alias)}
+	if action == ChangeCreate {
+		c.phase = phaseAliasCreate
+		c.apply = func(ctx context.Context, st *applyState) error {
+			return st.c.User.AddEmailAlias(ctx, AddEmailAliasReq{BaseUserReq: BaseUserReq{Email: email}, Alias: alias})
+		}
+	} else {
+		c.phase = phaseAliasDelete
+		c.apply = func(ctx context.Context, st *applyState) error {
+			return st.c.User.DeleteEmailAlias(ctx, DeleteEmailAliasReq{BaseUserReq: BaseUserReq{Email: email}, Alias: alias})
+		}
+	}
+	return c
+}
+
+func (p *directoryPlanner) planGroups(ctx context.Context) error {
+	live := map[string]Group{}
+	for g, err := range p.s.Group.All(ctx) {
+		if err != nil {
+			return fmt.Errorf("list groups: %w", err)
+		}
+		live[strings.ToLower(g.Email)] = g
+		p.st.groupIDs[strings.ToLower(g.Email)] = g.ID
+	}
+
+	declared := map[string]bool{}
+	for _, spec := range p.spec.Groups {
+		key := strings.ToLower(spec.Email)
+		declared[key] = true
+		members := lowerSorted(spec.Members)
+		g, exists := live[key]
+		if !exists {
+			var diffs []FieldDiff
+			diffs = diffString(diffs, "name", "", spec.Name)
+			diffs = diffBool(diffs, "hidden", false, spec.Hidden)
+			p.add(&DirectoryChange{
+				Action:  ChangeCreate,
+				Address: groupAddress(spec.Email),
+				Diffs:   diffs,
+				phase:   phaseGroupCreate,
+				apply: func(ctx context.Context, st *applyState) error {
+					req := CreateGroupReq{Name: spec.Name, Email: spec.Email}
+					if spec.Hidden != nil {
+						req.IsHidden = *spec.Hidden
+					}
+					group, err := st.c.Group.Create(ctx, req)
+					if err != nil {
+						return err
+					}
+					st.groupIDs[key] = group.ID
+					return nil
+				},
+			})
+			for _, m := range members {
+				p.add(memberChange(ChangeCreate, spec.Email, m))
+			}
+			continue
+		}
+
+		var (
+			diffs []FieldDiff
+			patch PatchGroupReq
+		)
+		if d := diffString(nil, "name", g.Name, spec.Name); len(d) > 0 {
+			diffs = append(diffs, d...)
+			patch.Name = Ptr(spec.Name)
+		}
+		if d := diffBool(nil, "hidden", g.IsHidden, spec.Hidden); len(d) > 0 {
+			diffs = append(diffs, d...)
+			patch.IsHidden = spec.Hidden
+		}
+		if len(diffs) > 0 {
+			id := g.ID
+			p.add(&DirectoryChange{
+				Action:  ChangeUpdate,
+				Address: groupAddress(spec.Email),
+				Diffs:   diffs,
+				phase:   phaseGroupUpdate,
+				apply: func(ctx context.Context, st *applyState) error {
+					patch.ID = id
+					return st.c.Group.Patch(ctx, patch)
+				},
+			})
+		}
+		if spec.Members == nil {
+			continue
+		}
+		var have []string
+		for m, err := range p.s.Group.AllMembers(ctx, g.ID) {
+			if err != nil {
+				return fmt.Errorf("list members of %s: %w", g.Email, err)
+			}
+			have = append(have, m.Email)
+		}
+		have = lowerSorted(have)
+		for _, m := range members {
+			if !slices.Contains(have, m) {
+				p.add(memberChange(ChangeCreate, spec.Email, m))
+			}
+		}
+		if p.opts.Prune {
+			for _, m := range have {
+				if !slices.Contains(members, m) {
+					p.add(memberChange(ChangeDelete, spec.Email, m))
+				}
+			}
+		}
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+	for key, g := range live {
+		if declared[key] {
+			continue
+		}
+		id := g.ID
+		p.add(&DirectoryChange{
+			Action:  ChangeDelete,
+			Address: groupAddress(g.Email),
+			phase:   phaseGroupDelete,
+			apply: func(ctx context.Context, st *applyState) error {
+				return st.c.Group.Delete(ctx, id)
+			},
+		})
+	}
+	return nil
+}
+
+func memberChange(action ChangeAction, group, member string) *DirectoryChange {
+	c := &DirectoryChange{Action: action, Address: memberAddress(group, member)}
+	groupID := func(st *applyState) (string, error) {
+		if id, ok := st.groupIDs[strings.ToLower(group)]; ok {
+			return id, nil
+		}
+		return "", fmt.Errorf("group %s not found", group)
+	}
+	if action == ChangeCreate {
+		c.phase = phaseMemberAdd
+		c.apply = func(ctx context.Context, st *applyState) error {
+			id, err := groupID(st)
+			if err != nil {
+				return err
+			}
+			return st.c.Group.AddMembers(ctx, id, []string{member})
+		}
+	} else {
+		c.phase = phaseMemberRemove
+		c.apply = func(ctx context.Context, st *applyState) error {
+			id, err := groupID(st)
+			if err != nil {
+				return err
+			}
+			return st.c.Group.RemoveMembers(ctx, id, []string{member})
+		}
+	}
+	return c
+}
+
+func (p *directoryPlanner) planSharedContacts(ctx context.Context) error {
+	tree, err := p.s.SharedContactFolder.Tree(ctx, "")
+	if err != nil {
+		return err
+	}
+	folders := map[string]*SharedContactFolderNode{}
+	var walk func(n *SharedContactFolderNode, path string)
+	walk = func(n *SharedContactFolderNode, path string) {
+		folders[path] = n
+		p.st.folderIDs[path] = n.ID
+		for _, c := range n.Children {
+			if path == "" {
+				walk(c, c.Name)
+			} else {
+				walk(c, path+"/"+c.Name)
+			}
+		}
+	}
+	walk(tree, "")
+
+	live := map[string]SharedContact{}
+	for folderPath, n := range folders {
+		for c, err := range p.s.SharedContact.AllByFolder(ctx, n.ID) {
+			if err != nil {
+				return fmt.Errorf("list contacts of %q: %w", folderPath, err)
+			}
+			addr := contactAddress(folderPath, c.Email, c.Name)
+			live[addr] = c
+		}
+	}
+
+	wanted := map[string]bool{}
+	declared := map[string]bool{}
+	for _, spec := range p.spec.SharedContacts {
+		for _, a := range withAncestors(spec.Folder) {
+			wanted[a] = true
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(wanted)) {
+		if folders[key] != nil {
+			continue
+		}
+		depth := len(SplitDepartmentPath(key))
+		p.add(&DirectoryChange{
+			Action:  ChangeCreate,
+			Address: folderAddress(key),
+			phase:   phaseFolderCreate,
+			depth:   depth,
+			apply: func(ctx context.Context, st *applyState) error {
+				parentID, err := st.folderID(parentPath(key))
+				if err != nil {
+					return err
+				}
+				folder, err := st.c.SharedContactFolder.Create(ctx, SharedContactFolderReq{Name: SplitDepartmentPath(key)[depth-1], ParentID: parentID})
+				if err != nil {
+					return err
+				}
+				st.folderIDs[key] = folder.ID
+				return nil
+			},
+		})
+	}
+
+	for _, spec := range p.spec.SharedContacts {
+		addr := contactAddress(spec.Folder, spec.Email, spec.Name)
+		declared[addr] = true
+		folder := strings.Join(SplitDepartmentPath(spec.Folder), "/")
+		want := SharedContactReq{
+			Name:        spec.Name,
+			Email:       spec.Email,
+			Phone:       spec.Phone,
+			WorkPhone:   spec.WorkPhone,
+			CompanyName: spec.CompanyName,
+			JobTitle:    spec.JobTitle,
+		}
+		c, exists := live[addr]
+		if !exists {
+			var diffs []FieldDiff
+			diffs = diffString(diffs, "name", "", spec.Name)
+			diffs = diffString(diffs, "email", "", spec.Email)
+			diffs = diffString(diffs, "phone", "", spec.Phone)
+			diffs = diffString(diffs, "workPhone", "", spec.WorkPhone)
+			diffs = diffString(diffs, "companyName", "", spec.CompanyName)
+			diffs = diffString(diffs, "jobTitle", "", spec.JobTitle)
+			p.add(&DirectoryChange{
+				Action:  ChangeCreate,
+				Address: addr,
+				Diffs:   diffs,
+				phase:   phaseContactCreate,
+				apply: func(ctx context.Context, st *applyState) error {
+					folderID, err := st.folderID(folder)
+					if err != nil {
+						return err
+					}
+					want.FolderID = folderID
+					_, err = st.c.SharedContact.Create(ctx, want)
+					return err
+				},
+			})
+			continue
+		}
+		var diffs []FieldDiff
+		diffs = diffString(diffs, "name", c.Name, spec.Name)
+		diffs = diffString(diffs, "phone", c.Phone, spec.Phone)
+		diffs = diffString(diffs, "workPhone", c.WorkPhone, spec.WorkPhone)
+		diffs = diffString(diffs, "companyName", c.CompanyName, spec.CompanyName)
+		diffs = diffString(diffs, "jobTitle", c.JobTitle, spec.JobTitle)
+		if len(diffs) == 0 {
+			continue
+		}
+		// 只发送有差异的字段，未声明的字段保持线上的值
+		req := UpdateSharedContactReq{ID: c.ID}
+		for _, d := range diffs {
+			v := Ptr(d.New)
+			switch d.Field {
+			case "name":
+				req.Name = v
+			case "phone":
+				req.Phone = v
+			case "workPhone":
+				req.WorkPhone = v
+			case "companyName":
+				req.CompanyName = v
+			case "jobTitle":
+				req.JobTitle = v
+			}
+		}
+		p.add(&DirectoryChange{
+			Action:  ChangeUpdate,
+			Address: addr,
+			Diffs:   diffs,
+			phase:   phaseContactUpdate,
+			apply: func(ctx context.Context, st *applyState) error {
+				_, err := st.c.SharedContact.Update(ctx, req)
+				return err
+			},
+		})
+	}
+
+	if !p.opts.Prune {
+		return nil
+	}
+	for addr, c := range live {
+		if declared[addr] {
+			continue
+		}
+		id := c.ID
+		p.add(&DirectoryChange{
+			Action:  ChangeDelete,
+			Address: addr,
+			phase:   phaseContactDelete,
+			apply: func(ctx context.Context, st *applyState) error {
+				return st.c.SharedContact.Delete(ctx, id)
+			},
+		})
+	}
+	for key, n := range folders {
+		if key == "" || wanted[key] {
+			continue
+		}
+		id := n.ID
+		p.add(&DirectoryChange{
+			Action:  ChangeDelete,
+			Address: folderAddress(key),
+			phase:   phaseFolderDelete,
+			depth:   -len(SplitDepartmentPath(key)),
+			apply: func(ctx context.Context, st *applyState) error {
+				return st.c.SharedContactFolder.Delete(ctx, id)
+			},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package alimail
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestProtectedBy(t *testing.T) {
+	patterns := []string{"department:高管*", "contact-folder:*/财务", "user:ceo@example.com", "group:all@*"}
+	cases := []struct {
+		addr string
+		want bool
+	}{
+		{"department:高管", true},
+		{"department:高管层/秘书处", true},
+		{"department:研发/高管", false},
+		{"contact-folder:总部/财务", true},
+		{"contact:总部/财务/a@example.com", true},
+		{"contact:总部/行政/a@example.com", false},
+		{"user:ceo@example.com", true},
+		{"alias:ceo@example.com/boss@example.com", true},
+		{"alias:cto@example.com/boss@example.com", false},
+		{"group-member:all@example.com/a@example.com", true},
+	}
+	for _, c := range cases {
+		if got := protectedBy(patterns, c.addr); got != c.want {
+			t.Errorf("protectedBy(%q) = %v, want %v", c.addr, got, c.want)
+		}
+	}
+}
+
+func TestDirectoryPlanSkipsProtectedChanges(t *testing.T) {
+	// 部门树与 reorgDirectory 相同：研发(A)/平台(A1)、产品(B)，u1 在研发
+	listByIds := func(req recordedRequest) (int, any) {
+		if req.Path == "/v2/users/listByIds" {
+			return http.StatusOK, listUserByIdsResponse{Users: []User{}}
+		}
+		return 0, nil
+	}
+	c, requests := newFakeServer(t, listByIds, reorgDirectory)
+	ctx := context.Background()
+	spec := DirectorySpec{
+		Departments: []DepartmentSpec{{Path: "研发/平台"}, {Path: "研发/新组"}},
+		Users:       []UserSpec{{Email: "u1@example.com", Name: "张三", Departments: []string{"研发"}}},
+		Protected:   []string{"department:研*", "user:u1@*"},
+	}
+	plan, err := c.Directory.Plan(ctx, spec, PlanOptions{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, ch := range plan.Changes {
+		got[string(ch.Action)+" "+ch.Address] = ch.Protected
+	}
+	want := map[string]bool{
+		"create department:研发/新组":    true,
+		"update user:u1@example.com": true,
+		"delete department:产品":       false,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+	if create, update, del := plan.Counts(); create != 0 || update != 0 || del != 1 {
+		t.Errorf("counts = %d, %d, %d", create, update, del)
+	}
+
+	rst, err := plan.Apply(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rst.Applied != 1 || rst.Skipped != 2 {
+		t.Errorf("applied %d, skipped %d", rst.Applied, rst.Skipped)
+	}
+	w := writes(requests())
+	if len(w) != 1 || w[0].Method != MethodDelete || w[0].Path != "/v2/departments/B" {
+		t.Errorf("writes = %+v", w)
+	}
+}
+
+func TestDirectoryPlanUnchangedMultiDepartmentUser(t *testing.T) {
+	// visibilityDirectory 中 u1 同时属于研发与高管，上级只在 managerInfo 中
+	c, _ := newFakeServer(t, visibilityDirectory)
+	spec := DirectorySpec{
+		Users: []UserSpec{{Email: "u1@example.com", Name: "张三", Departments: []string{"高管", "研发"}, Manager: "u2@example.com"}},
+	}
+	plan, err := c.Directory.Plan(context.Background(), spec, PlanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range plan.Changes {
+		t.Errorf("unexpected change %s %s: %+v", ch.Action, ch.Address, ch.Diffs)
+	}
+}
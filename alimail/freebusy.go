@@ -0,0 +1,433 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimeRange 时间区间，左闭右开
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Overlaps 判断两个区间是否有重叠
+func (r TimeRange) Overlaps(o TimeRange) bool {
+	return r.Start.Before(o.End) && o.Start.Before(r.End)
+}
+
+// maxOccurrenceScan 展开重复日程时最多检查的候选次数，防止错误的规则导致死循环
+const maxOccurrenceScan = 100000
+
+// Occurrences 展开日程在 [from, to) 内的所有实例，非重复日程最多返回一个
+func (e CalendarEvent) Occurrences(from, to time.Time) ([]TimeRange, error) {
+	start, err := e.Start.Time()
+	if err != nil {
+		return nil, fmt.Errorf("invalid start of event %q: %w", e.Summary, err)
+	}
+	end, err := e.End.Time()
+	if err != nil {
+		return nil, fmt.Errorf("invalid end of event %q: %w", e.Summary, err)
+	}
+	if e.IsAllDay && !end.After(start) {
+		end = start.AddDate(0, 0, 1)
+	}
+	dur := end.Sub(start)
+	window := TimeRange{Start: from, End: to}
+
+	if e.Recurrence == nil {
+		if (TimeRange{Start: start, End: end}).Overlaps(window) {
+			return []TimeRange{{Start: start, End: end}}, nil
+		}
+		return nil, nil
+	}
+
+	r := e.Recurrence
+	interval := r.Pattern.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	var until time.Time
+	if r.Range.Type == RecurrenceRangeEndDate && r.Range.EndDate != "" {
+		d, err := time.ParseInLocation("2006-01-02", r.Range.EndDate, start.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence end date %q", r.Range.EndDate)
+		}
+		until = d.AddDate(0, 0, 1)
+	}
+	excluded := make(map[string]bool, len(r.ExceptionDates))
+	for _, d := range r.ExceptionDates {
+		excluded[d] = true
+	}
+
+	var (
+		result []TimeRange
+		count  int
+	)
+	// emit 按时间顺序处理候选实例，返回 false 表示不再继续
+	emit := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !until.IsZero() && !t.Before(until) {
+			return false
+		}
+		if !t.Before(to) {
+			return false
+		}
+		count++
+		if r.Range.Type == RecurrenceRangeNumbered && count > r.Range.NumberOfOccurrences {
+			return false
+		}
+		if excluded[t.Format("2006-01-02")] {
+			return true
+		}
+		occ := TimeRange{Start: t, End: t.Add(dur)}
+		if occ.Overlaps(window) {
+			result = append(result, occ)
+		}
+		return true
+	}
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	switch r.Pattern.Type {
+	case RecurrenceDaily:
+		for i := 0; i < maxOccurrenceScan; i += interval {
+			if !emit(at(start.Year(), start.Month(), start.Day()+i)) {
+				break
+			}
+		}
+	case RecurrenceWeekly:
+		days := map[time.Weekday]bool{}
+		for _, d := range r.Pattern.DaysOfWeek {
+			wd, ok := parseWeekday(d)
+			if !ok {
+				return nil, fmt.Errorf("invalid day of week %q", d)
+			}
+			days[wd] = true
+		}
+		if len(days) == 0 {
+			days[start.Weekday()] = true
+		}
+		weekStart := at(start.Year(), start.Month(), start.Day()-int(start.Weekday()))
+	weekly:
+		for w := 0; w < maxOccurrenceScan; w += interval {
+			for wd := time.Sunday; wd <= time.Saturday; wd++ {
+				if !days[wd] {
+					continue
+				}
+				if !emit(at(weekStart.Year(), weekStart.Month(), weekStart.Day()+w*7+int(wd))) {
+					break weekly
+				}
+			}
+		}
+	case RecurrenceAbsoluteMonthly, RecurrenceRelativeMonthly:
+		for i := 0; i < maxOccurrenceScan; i += interval {
+			first := time.Date(start.Year(), start.Month()+time.Month(i), 1, 0, 0, 0, 0, start.Location())
+			var t time.Time
+			var ok bool
+			if r.Pattern.Type == RecurrenceAbsoluteMonthly {
+				t, ok = dayOfMonth(first, r.Pattern.DayOfMonth, at)
+			} else {
+				t, ok = nthWeekday(first, r.Pattern.Index, r.Pattern.DaysOfWeek, at)
+			}
+			if ok && !emit(t) {
+				break
+			}
+		}
+	case RecurrenceAbsoluteYearly:
+		month := time.Month(r.Pattern.Month)
+		if month == 0 {
+			month = start.Month()
+		}
+		for i := 0; i < maxOccurrenceScan; i += interval {
+			first := time.Date(start.Year()+i, month, 1, 0, 0, 0, 0, start.Location())
+			t, ok := dayOfMonth(first, r.Pattern.DayOfMonth, at)
+			if ok && !emit(t) {
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported recurrence type %q", r.Pattern.Type)
+	}
+	return result, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(wd.String(), s) {
+			return wd, true
+		}
+	}
+	return 0, false
+}
+
+// dayOfMonth 返回当月第 day 天，当月没有这一天时返回 false
+func dayOfMonth(first time.Time, day int, at func(int, time.Month, int) time.Time) (time.Time, bool) {
+	t := at(first.Year(), first.Month(), day)
+	return t, day > 0 && t.Month() == first.Month()
+}
+
+// nthWeekday 返回当月第 index 个指定星期几
+func nthWeekday(first time.Time, index string, days []string, at func(int, time.Month, int) time.Time) (time.Time, bool) {
+	if len(days) == 0 {
+		return time.Time{}, false
+	}
+	wd, ok := parseWeekday(days[0])
+	if !ok {
+		return time.Time{}, false
+	}
+	offset := (int(wd) - int(first.Weekday()) + 7) % 7
+	n := map[string]int{"first": 0, "second": 1, "third": 2, "fourth": 3}
+	if index == "last" {
+		lastDay := first.AddDate(0, 1, -1)
+		back := (int(lastDay.Weekday()) - int(wd) + 7) % 7
+		return at(lastDay.Year(), lastDay.Month(), lastDay.Day()-back), true
+	}
+	k, ok := n[index]
+	if !ok {
+		return time.Time{}, false
+	}
+	t := at(first.Year(), first.Month(), 1+offset+7*k)
+	return t, t.Month() == first.Month()
+}
+
+// FreeBusyReq 查询忙闲的参数
+type FreeBusyReq struct {
+	Emails    []string  // 需要查询的用户邮箱
+	StartTime time.Time // 开始时间
+	EndTime   time.Time // 结束时间
+}
+
+// FreeBusy 单个用户的忙闲信息
+type FreeBusy struct {
+	Email string      // 用户邮箱
+	Name  string      // 用户姓名
+	Busy  []TimeRange // 忙碌时段，已合并且按开始时间排序
+	Err   error       // 查询失败的原因，不为空时 Busy 无意义
+}
+
+// freeBusyConcurrency 同时查询忙闲的用户数
+const freeBusyConcurrency = 5
+
+// FreeBusy 查询多个用户在时间范围内的忙闲。
+// 用户必须存在于通讯录中且未被冻结；显示为空闲（showAs=free）的日程不计为忙碌。
+func (c *CalendarService) FreeBusy(ctx context.Context, req FreeBusyReq) ([]FreeBusy, error) {
+	if len(req.Emails) == 0 {
+		return nil, fmt.Errorf("emails can't be empty")
+	}
+	if !req.EndTime.After(req.StartTime) {
+		return nil, fmt.Errorf("endTime must be after startTime")
+	}
+
+	result := make([]FreeBusy, len(req.Emails))
+	sem := make(chan struct{}, freeBusyConcurrency)
+	var wg sync.WaitGroup
+	for i, email := range req.Emails {
+		wg.Add(1)
+		go func(i int, email string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result[i] = c.userFreeBusy(ctx, email, req.StartTime, req.EndTime)
+		}(i, email)
+	}
+	wg.Wait()
+	return result, ctx.Err()
+}
+
+func (c *CalendarService) userFreeBusy(ctx context.Context, email string, start, end time.Time) FreeBusy {
+	fb := FreeBusy{Email: email}
+	user, err := c.User.Get(ctx, BaseUserReq{Email: email})
+	if err != nil {
+		fb.Err = err
+		return fb
+	}
+	fb.Name = user.Name
+	if user.Status == FREEZE {
+		fb.Err = fmt.Errorf("user %s is frozen", email)
+		return fb
+	}
+
+	calendars, err := c.List(ctx, email)
+	if err != nil {
+		fb.Err = err
+		return fb
+	}
+	var busy []TimeRange
+	for _, cal := range calendars {
+		events, err := c.ListEvents(ctx, ListEventsReq{Email: email, CalendarID: cal.ID, StartTime: start, EndTime: end})
+		if err != nil {
+			fb.Err = fmt.Errorf("calendar %s: %w", cal.ID, err)
+			return fb
+		}
+		for _, e := range events {
+			if e.ShowAs == "free" {
+				continue
+			}
+			occ, err := e.Occurrences(start, end)
+			if err != nil {
+				fb.Err = err
+				return fb
+			}
+			busy = append(busy, occ...)
+		}
+	}
+	fb.Busy = mergeTimeRanges(busy)
+	return fb
+}
+
+// mergeTimeRanges 合并重叠或相邻的区间
+func mergeTimeRanges(ranges []TimeRange) []TimeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start.Before(ranges[j].Start) })
+	merged := []TimeRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if !r.Start.After(last.End) {
+			if r.End.After(last.End) {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// WorkingHours 每天的工作时间，以距离当天零点的时长表示
+type WorkingHours struct {
+	Start time.Duration // 如 9 * time.Hour
+	End   time.Duration // 如 18 * time.Hour
+}
+
+// FindMeetingSlotsReq 查找会议时间的参数
+type FindMeetingSlotsReq struct {
+	Attendees    []string                  // 参会人邮箱
+	Duration     time.Duration             // 会议时长
+	StartTime    time.Time                 // 搜索开始时间
+	EndTime      time.Time                 // 搜索结束时间
+	WorkingHours WorkingHours              // 工作时间，默认 9:00-18:00
+	Weekdays     []time.Weekday            // 工作日，默认周一到周五
+	Location     *time.Location            // 默认时区，默认为 StartTime 的时区
+	TimeZones    map[string]*time.Location // 参会人各自的时区，未设置的使用 Location
+	Step         time.Duration             // 候选时间的间隔，默认 30 分钟
+	MinAttendees int                       // 至少需要空闲的人数，默认 1
+	MaxResults   int                       // 最多返回的候选数，默认 10
+}
+
+// MeetingSlot 候选会议时间
+type MeetingSlot struct {
+	TimeRange
+	Free        []string // 空闲且在工作时间内的参会人
+	Unavailable []string // 忙碌、不在工作时间或忙闲查询失败的参会人
+}
+
+// FindMeetingSlots 根据参会人的忙闲和工作时间查找候选会议时间，
+// 按空闲人数从多到少排序，人数相同时时间早的在前
+func (c *CalendarService) FindMeetingSlots(ctx context.Context, req FindMeetingSlotsReq) ([]MeetingSlot, error) {
+	if req.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if req.WorkingHours == (WorkingHours{}) {
+		req.WorkingHours = WorkingHours{Start: 9 * time.Hour, End: 18 * time.Hour}
+	}
+	if req.WorkingHours.End <= req.WorkingHours.Start {
+		return nil, fmt.Errorf("invalid working hours")
+	}
+	if len(req.Weekdays) == 0 {
+		req.Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	}
+	if req.Location == nil {
+		req.Location = req.StartTime.Location()
+	}
+	if req.Step <= 0 {
+		req.Step = 30 * time.Minute
+	}
+	if req.MinAttendees <= 0 {
+		req.MinAttendees = 1
+	}
+	if req.MaxResults <= 0 {
+		req.MaxResults = 10
+	}
+
+	fbs, err := c.FreeBusy(ctx, FreeBusyReq{Emails: req.Attendees, StartTime: req.StartTime, EndTime: req.EndTime})
+	if err != nil {
+		return nil, err
+	}
+	return req.slots(fbs), nil
+}
+
+// slots 在已查询到的忙闲上枚举候选时间，req 已填充默认值
+func (req FindMeetingSlotsReq) slots(fbs []FreeBusy) []MeetingSlot {
+	var slots []MeetingSlot
+	for t := alignSlot(req.StartTime, req.Step); !t.Add(req.Duration).After(req.EndTime); t = t.Add(req.Step) {
+		slot := MeetingSlot{TimeRange: TimeRange{Start: t, End: t.Add(req.Duration)}}
+		for _, fb := range fbs {
+			loc := req.Location
+			if l, ok := req.TimeZones[fb.Email]; ok && l != nil {
+				loc = l
+			}
+			if fb.Err == nil && req.withinWorkingHours(slot.TimeRange, loc) && !isBusy(fb.Busy, slot.TimeRange) {
+				slot.Free = append(slot.Free, fb.Email)
+			} else {
+				slot.Unavailable = append(slot.Unavailable, fb.Email)
+			}
+		}
+		if len(slot.Free) >= req.MinAttendees {
+			slots = append(slots, slot)
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool { return len(slots[i].Free) > len(slots[j].Free) })
+	if len(slots) > req.MaxResults {
+		slots = slots[:req.MaxResults]
+	}
+	return slots
+}
+
+// alignSlot 返回不早于 t 的第一个候选时间，候选时间按 t 所在时区当天零点起每隔 step 对齐，
+// 避免 time.Truncate 按 UTC 对齐导致 +05:30 等非整点时区的候选时间落在奇怪的分钟上
+func alignSlot(t time.Time, step time.Duration) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	n := (t.Sub(day) + step - 1) / step
+	return day.Add(n * step)
+}
+
+// withinWorkingHours 判断时段在某个时区下是否完整落在同一天的工作时间内
+func (req FindMeetingSlotsReq) withinWorkingHours(r TimeRange, loc *time.Location) bool {
+	start := r.Start.In(loc)
+	end := r.End.In(loc)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	workday := false
+	for _, wd := range req.Weekdays {
+		if wd == start.Weekday() {
+			workday = true
+			break
+		}
+	}
+	if !workday {
+		return false
+	}
+	return !start.Before(day.Add(req.WorkingHours.Start)) && !end.After(day.Add(req.WorkingHours.End))
+}
+
+func isBusy(busy []TimeRange, r TimeRange) bool {
+	for _, b := range busy {
+		if b.Overlaps(r) {
+			return true
+		}
+		if !b.Start.Before(r.End) {
+			break
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package alimail
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAlignSlotUsesLocalMidnight(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	cases := []struct {
+		in, want time.Time
+		step     time.Duration
+	}{
+		{time.Date(2024, 3, 4, 9, 10, 0, 0, kolkata), time.Date(2024, 3, 4, 9, 30, 0, 0, kolkata), 30 * time.Minute},
+		{time.Date(2024, 3, 4, 9, 0, 0, 0, kolkata), time.Date(2024, 3, 4, 9, 0, 0, 0, kolkata), time.Hour},
+		{time.Date(2024, 3, 4, 9, 1, 0, 0, kolkata), time.Date(2024, 3, 4, 10, 0, 0, 0, kolkata), time.Hour},
+		{time.Date(2024, 3, 4, 23, 50, 0, 0, time.UTC), time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), 15 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := alignSlot(c.in, c.step); !got.Equal(c.want) {
+			t.Errorf("alignSlot(%v, %v) = %v, want %v", c.in, c.step, got, c.want)
+		}
+	}
+}
+
+func TestMeetingSlots(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(h, m int) time.Time { return time.Date(2024, 3, 4, h, m, 0, 0, shanghai) } // 周一
+	req := FindMeetingSlotsReq{
+		Duration:     time.Hour,
+		StartTime:    at(9, 10),
+		EndTime:      at(13, 0),
+		WorkingHours: WorkingHours{Start: 9 * time.Hour, End: 12 * time.Hour},
+		Weekdays:     []time.Weekday{time.Monday},
+		Location:     shanghai,
+		Step:         30 * time.Minute,
+		MinAttendees: 1,
+		MaxResults:   10,
+	}
+	fbs := []FreeBusy{
+		{Email: "a@example.com", Busy: []TimeRange{{Start: at(10, 0), End: at(10, 30)}}},
+		{Email: "b@example.com"},
+		{Email: "c@example.com", Err: errors.New("frozen")},
+	}
+	var got []string
+	for _, s := range req.slots(fbs) {
+		got = append(got, s.Start.Format("15:04")+" "+s.Free[0]+" "+s.Unavailable[len(s.Unavailable)-1])
+	}
+	// 9:10 对齐到 9:30；a 在 10:00-10:30 忙，两人都空闲的时段排在最前；11:30 起超出工作时间
+	want := []string{
+		"10:30 a@example.com c@example.com",
+		"11:00 a@example.com c@example.com",
+		"09:30 b@example.com c@example.com",
+		"10:00 b@example.com c@example.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("slots = %q, want %q", got, want)
+	}
+}
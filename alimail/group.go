@@ -1,6 +1,9 @@
 package alimail
 
 import (
+	"context"
+	"fmt"
+	"iter"
 	"time"
 )
 
@@ -27,3 +30,144 @@ type Group struct {
 	LastRecvMessageTime       time.Time `json:"lastRecvMessageTime"`
 	IgnoreAutoReplyOfMember   bool      `json:"ignoreAutoReplyOfMember"`
 }
+
+// CreateGroupReq 创建邮件组的参数
+type CreateGroupReq struct {
+	Name     string   `json:"name"`               // 邮件组名称
+	Email    string   `json:"email"`              // 邮件组地址
+	Admins   []string `json:"admins,omitempty"`   // 管理员邮箱
+	IsHidden bool     `json:"isHidden,omitempty"` // 是否隐藏
+}
+
+// Create 创建邮件组
+func (g *GroupService) Create(ctx context.Context, req CreateGroupReq) (*Group, error) {
+	if req.Name == "" || req.Email == "" {
+		return nil, fmt.Errorf("name and email are required")
+	}
+	var group Group
+	if err := g.doJSON(ctx, MethodPost, "/v2/groups", req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Get 获取邮件组，id 可以是邮件组ID或邮件组地址
+func (g *GroupService) Get(ctx context.Context, id string) (*Group, error) {
+	if id == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	var group Group
+	if err := g.doJSON(ctx, MethodGet, "/v2/groups/"+id, nil, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// PatchGroupReq 部分更新邮件组的参数，只发送非 nil 的字段
+type PatchGroupReq struct {
+	ID       string    `json:"-"`                  // 邮件组ID或地址
+	Name     *string   `json:"name,omitempty"`     // 邮件组名称
+	Admins   *[]string `json:"admins,omitempty"`   // 管理员邮箱
+	IsHidden *bool     `json:"isHidden,omitempty"` // 是否隐藏
+}
+
+// Patch 部分更新邮件组
+func (g *GroupService) Patch(ctx context.Context, req PatchGroupReq) error {
+	if req.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if req.Name != nil && *req.Name == "" {
+		return fmt.Errorf("name can't be set to empty")
+	}
+	return g.doJSON(ctx, MethodPatch, "/v2/groups/"+req.ID, req, nil)
+}
+
+// Delete 删除邮件组
+func (g *GroupService) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("id is required")
+	}
+	return g.doJSON(ctx, MethodDelete, "/v2/groups/"+id, nil, nil)
+}
+
+// ListGroupsRsp 邮件组列表的返回
+type ListGroupsRsp struct {
+	Groups []Group `json:"groups"`
+	Total  int     `json:"total"`
+}
+
+// List 分页获取邮件组
+func (g *GroupService) List(ctx context.Context, offset, limit int) (rst ListGroupsRsp, err error) {
+	if limit > MaxPageSize {
+		return rst, fmt.Errorf("limit can't be more than 100")
+	}
+	path := fmt.Sprintf("/v2/groups?offset=%d&limit=%d", offset, limit)
+	err = g.doJSON(ctx, MethodGet, path, nil, &rst)
+	return rst, err
+}
+
+// All 遍历全部邮件组，自动处理分页
+func (g *GroupService) All(ctx context.Context, opts ...PaginatorOption) iter.Seq2[Group, error] {
+	return NewPaginator(func(ctx context.Context, offset, limit int) ([]Group, int, error) {
+		rsp, err := g.List(ctx, offset, limit)
+		return rsp.Groups, rsp.Total, err
+	}, opts...).All(ctx)
+}
+
+// GroupMember 邮件组成员
+type GroupMember struct {
+	Email string `json:"email"` // 成员邮箱，可以是用户、其它邮件组或外部地址
+	Name  string `json:"name"`  // 成员名称
+	Type  string `json:"type"`  // 成员类型
+}
+
+// ListGroupMembersRsp 邮件组成员列表的返回
+type ListGroupMembersRsp struct {
+	Members []GroupMember `json:"members"`
+	Total   int           `json:"total"`
+}
+
+// ListMembers 分页获取邮件组成员
+func (g *GroupService) ListMembers(ctx context.Context, id string, offset, limit int) (rst ListGroupMembersRsp, err error) {
+	if id == "" {
+		return rst, fmt.Errorf("id is required")
+	}
+	if limit > MaxPageSize {
+		return rst, fmt.Errorf("limit can't be more than 100")
+	}
+	path := fmt.Sprintf("/v2/groups/%s/members?offset=%d&limit=%d", id, offset, limit)
+	err = g.doJSON(ctx, MethodGet, path, nil, &rst)
+	return rst, err
+}
+
+// AllMembers 遍历邮件组的全部成员，自动处理分页
+func (g *GroupService) AllMembers(ctx context.Context, id string, opts ...PaginatorOption) iter.Seq2[GroupMember, error] {
+	return NewPaginator(func(ctx context.Context, offset, limit int) ([]GroupMember, int, error) {
+		rsp, err := g.ListMembers(ctx, id, offset, limit)
+		return rsp.Members, rsp.Total, err
+	}, opts...).All(ctx)
+}
+
+// AddMembers 向邮件组添加成员
+func (g *GroupService) AddMembers(ctx context.Context, id string, emails []string) error {
+	return g.modifyMembers(ctx, MethodPost, id, emails)
+}
+
+// RemoveMembers 从邮件组移除成员
+func (g *GroupService) RemoveMembers(ctx context.Context, id string, emails []string) error {
+	return g.modifyMembers(ctx, MethodDelete, id, emails)
+}
+
+func (g *GroupService) modifyMembers(ctx context.Context, method, id string, emails []string) error {
+	if id == "" {
+		return fmt.Errorf("id is required")
+	}
+	if len(emails) == 0 {
+		return nil
+	}
+	members := make([]GroupMember, len(emails))
+	for i, email := range emails {
+		members[i] = GroupMember{Email: email}
+	}
+	return g.doJSON(ctx, method, fmt.Sprintf("/v2/groups/%s/members", id), map[string]any{"members": members}, nil)
+}
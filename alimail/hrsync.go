@@ -0,0 +1,504 @@
+package alimail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 以人事系统导出的员工名单为准，同步 AliMail 中的用户与部门：
+// 新员工创建帐号，调岗员工更新信息，离职员工冻结帐号。
+
+// EmployeeStatus 员工状态
+type EmployeeStatus string
+
+const (
+	EmployeeActive   EmployeeStatus = "active"   // 在职
+	EmployeeInactive EmployeeStatus = "inactive" // 离职
+)
+
+// ParseEmployeeStatus 解析人事系统中的员工状态，空值视为在职
+func ParseEmployeeStatus(s string) (EmployeeStatus, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "active", "employed", "在职":
+		return EmployeeActive, nil
+	case "inactive", "terminated", "left", "leaver", "离职":
+		return EmployeeInactive, nil
+	}
+	return "", fmt.Errorf("unknown employee status %q", s)
+}
+
+// Employee 人事系统中的一名员工。除 Email、Name 外的字段为空表示名单中没有该信息，
+// 同步时不会用空值覆盖帐号中的已有信息
+type Employee struct {
+	Email      string         `json:"email"`
+	Name       string         `json:"name"`
+	EmployeeNo string         `json:"employeeNo"`
+	Department string         `json:"department"` // 部门名称路径，如 "研发中心/平台部"，为空时新员工放在根部门，已有帐号不调整部门
+	JobTitle   string         `json:"jobTitle"`
+	Manager    string         `json:"manager"` // 上级邮箱
+	Status     EmployeeStatus `json:"status"`
+}
+
+// employeeColumns CSV 表头与字段的对应关系，表头不区分大小写
+var employeeColumns = map[string]func(e *Employee, v string) error{
+	"email":      func(e *Employee, v string) error { e.Email = v; return nil },
+	"name":       func(e *Employee, v string) error { e.Name = v; return nil },
+	"employeeno": func(e *Employee, v string) error { e.EmployeeNo = v; return nil },
+	"department": func(e *Employee, v string) error { e.Department = v; return nil },
+	"jobtitle":   func(e *Employee, v string) error { e.JobTitle = v; return nil },
+	"title":      func(e *Employee, v string) error { e.JobTitle = v; return nil },
+	"manager":    func(e *Employee, v string) error { e.Manager = v; return nil },
+	"status": func(e *Employee, v string) (err error) {
+		e.Status, err = ParseEmployeeStatus(v)
+		return err
+	},
+}
+
+// ReadEmployeesCSV 读取带表头的 CSV 员工名单，表头为 email,name,employeeNo,department,jobTitle,manager,status，
+// 顺序不限，email 与 name 必须存在，未知的列会被忽略；缺少的列不会在同步时修改帐号的对应信息
+func ReadEmployeesCSV(r io.Reader) ([]Employee, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	setters := make([]func(*Employee, string) error, len(header))
+	var hasEmail, hasName bool
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		setters[i] = employeeColumns[h]
+		hasEmail = hasEmail || h == "email"
+		hasName = hasName || h == "name"
+	}
+	if !hasEmail || !hasName {
+		return nil, fmt.Errorf("csv header must contain email and name")
+	}
+	var list []Employee
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		e := Employee{Status: EmployeeActive}
+		for i, v := range rec {
+			if i < len(setters) && setters[i] != nil {
+				if err := setters[i](&e, strings.TrimSpace(v)); err != nil {
+					line, _ := cr.FieldPos(i)
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+// ReadEmployeesJSON 读取 JSON 数组格式的员工名单
+func ReadEmployeesJSON(r io.Reader) ([]Employee, error) {
+	var list []Employee
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode employees: %w", err)
+	}
+	for i := range list {
+		status, err := ParseEmployeeStatus(string(list[i].Status))
+		if err != nil {
+			return nil, fmt.Errorf("employee %s: %w", list[i].Email, err)
+		}
+		list[i].Status = status
+	}
+	return list, nil
+}
+
+// HRSyncThresholds 单次同步的变更上限，超过任一上限时中止且不做任何修改；为 0 表示不限制
+type HRSyncThresholds struct {
+	MaxFreezeRatio float64 // 冻结数占现有正常帐号数的比例上限，如 0.05
+	MaxFreezeCount int     // 冻结数上限
+	MaxCreateCount int     // 新建数上限
+	MaxUpdateRatio float64 // 更新数占现有正常帐号数的比例上限
+
+	MaxUnfreezeCount int // 解冻数上限
+}
+
+// DefaultHRSyncThresholds 默认上限：冻结不超过 5%，更新不超过 20%
+var DefaultHRSyncThresholds = HRSyncThresholds{MaxFreezeRatio: 0.05, MaxUpdateRatio: 0.2}
+
+// ErrThresholdExceeded 计划的变更超过了上限
+var ErrThresholdExceeded = errors.New("hr sync threshold exceeded")
+
+// HRSyncReq 同步参数
+type HRSyncReq struct {
+	Employees  []Employee
+	Thresholds *HRSyncThresholds // 为 nil 时使用 DefaultHRSyncThresholds
+	// Ignore 不参与同步的帐号邮箱，支持 path.Match 通配符，如管理员与服务帐号 "admin@*"、"noreply-*@example.com"
+	Ignore       []string
+	FreezeReason string    // 冻结原因，默认为 "left company (hr sync)"
+	DryRun       bool      // 只计算变更并写入日志，不做任何修改
+	Journal      io.Writer // 每行一条 JSON 格式的变更记录，为 nil 时不写
+}
+
+// HRSyncAction 同步动作
+type HRSyncAction string
+
+const (
+	HRSyncCreate   HRSyncAction = "create"   // 新员工
+	HRSyncUpdate   HRSyncAction = "update"   // 信息或部门变化
+	HRSyncFreeze   HRSyncAction = "freeze"   // 离职
+	HRSyncUnfreeze HRSyncAction = "unfreeze" // 重新入职
+	HRSyncAbort    HRSyncAction = "abort"    // 超过上限而中止
+)
+
+// HRSyncEntry 日志中的一条记录
+type HRSyncEntry struct {
+	Time   time.Time    `json:"time"`
+	RunID  string       `json:"runId"`
+	Action HRSyncAction `json:"action"`
+	Email  string       `json:"email,omitempty"`
+	Diffs  []FieldDiff  `json:"diffs,omitempty"`
+	Reason string       `json:"reason,omitempty"` // 冻结与解冻的原因
+	DryRun bool         `json:"dryRun,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// HRSyncReport 同步结果
+type HRSyncReport struct {
+	RunID   string
+	Entries []HRSyncEntry
+	Created int
+	Updated int
+	Frozen  int
+	Revived int // 解冻的帐号数
+	Failed  int
+}
+
+type hrChange struct {
+	action HRSyncAction
+	email  string
+	diffs  []FieldDiff
+	reason string
+	apply  func(ctx context.Context) error
+}
+
+// SyncEmployees 以员工名单为准同步用户：名单中不存在的帐号创建（随机初始密码，首次登录需修改），
+// 姓名、员工编号、职位、上级或部门变化的帐号更新（部门按路径查找，不存在时创建；名单中为空的字段不更新），
+// 离职或不在名单中的正常帐号冻结，名单中在职但已冻结的帐号解冻。
+// 变更超过上限时返回 ErrThresholdExceeded 且不做任何修改；单个帐号失败不影响其它帐号，失败记录在日志中。
+func (u *UserService) SyncEmployees(ctx context.Context, req HRSyncReq) (*HRSyncReport, error) {
+	employees, err := normalizeEmployees(req.Employees)
+	if err != nil {
+		return nil, err
+	}
+	thresholds := DefaultHRSyncThresholds
+	if req.Thresholds != nil {
+		thresholds = *req.Thresholds
+	}
+	reason := req.FreezeReason
+	if reason == "" {
+		reason = "left company (hr sync)"
+	}
+	ignored := func(email string) bool {
+		for _, p := range req.Ignore {
+			if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(email)); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	tree, err := u.Department.Tree(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	users, err := u.All(ctx, ListUsersReq{})
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	live := make(map[string]User, len(users))
+	active := 0
+	for _, user := range users {
+		live[strings.ToLower(user.Email)] = user
+		if user.Status != FREEZE && !ignored(user.Email) {
+			active++
+		}
+	}
+
+	var creates, updates, freezes, unfreezes []hrChange
+	managers := map[string]string{} // 新员工的上级，全部创建后再设置
+	for _, e := range employees {
+		if ignored(e.Email) {
+			continue
+		}
+		user, exists := live[strings.ToLower(e.Email)]
+		if e.Status == EmployeeInactive {
+			if exists && user.Status != FREEZE {
+				freezes = append(freezes, u.freezeChange(user.Email, reason))
+			}
+			continue
+		}
+		if !exists {
+			creates = append(creates, u.createChange(e))
+			if e.Manager != "" {
+				managers[e.Email] = e.Manager
+			}
+			continue
+		}
+		if user.Status == FREEZE {
+			unfreezes = append(unfreezes, u.unfreezeChange(user.Email, "rehired (hr sync)"))
+		}
+		if c, ok := u.updateChange(tree, user, e); ok {
+			updates = append(updates, c)
+		}
+	}
+	inFeed := make(map[string]bool, len(employees))
+	for _, e := range employees {
+		inFeed[strings.ToLower(e.Email)] = true
+	}
+	for _, user := range users {
+		if user.Status != FREEZE && !inFeed[strings.ToLower(user.Email)] && !ignored(user.Email) {
+			freezes = append(freezes, u.freezeChange(user.Email, reason))
+		}
+	}
+	for _, email := range slices.Sorted(maps.Keys(managers)) {
+		manager := managers[email]
+		updates = append(updates, hrChange{
+			action: HRSyncUpdate,
+			email:  email,
+			diffs:  []FieldDiff{{Field: "manager", New: manager}},
+			apply: func(ctx context.Context) error {
+				_, err := u.Patch(ctx, PatchUserReq{BaseUserReq: BaseUserReq{Email: email}, ManagerEmail: Ptr(manager)})
+				return err
+			},
+		})
+	}
+
+	report := &HRSyncReport{RunID: newRunID()}
+	journal := func(e HRSyncEntry) error {
+		e.Time = time.Now()
+		e.RunID = report.RunID
+		e.DryRun = req.DryRun
+		report.Entries = append(report.Entries, e)
+		if req.Journal == nil {
+			return nil
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = req.Journal.Write(append(line, '\n'))
+		return err
+	}
+
+	if err := checkThresholds(thresholds, active, len(creates), len(updates), len(freezes), len(unfreezes)); err != nil {
+		if jerr := journal(HRSyncEntry{Action: HRSyncAbort, Error: err.Error()}); jerr != nil {
+			return report, errors.Join(err, jerr)
+		}
+		return report, err
+	}
+
+	// 先建后改，解冻早于更新，最后冻结
+	all := slices.Concat(creates, unfreezes, updates, freezes)
+	for _, c := range all {
+		entry := HRSyncEntry{Action: c.action, Email: c.email, Diffs: c.diffs, Reason: c.reason}
+		if !req.DryRun {
+			if err := c.apply(ctx); err != nil {
+				entry.Error = err.Error()
+				report.Failed++
+			}
+		}
+		if entry.Error == "" {
+			switch c.action {
+			case HRSyncCreate:
+				report.Created++
+			case HRSyncUpdate:
+				report.Updated++
+			case HRSyncFreeze:
+				report.Frozen++
+			case HRSyncUnfreeze:
+				report.Revived++
+			}
+		}
+		if err := journal(entry); err != nil {
+			return report, fmt.Errorf("write journal: %w", err)
+		}
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+	}
+	return report, nil
+}
+
+// normalizeEmployees 检查必填字段与重复邮箱，并规范部门路径
+func normalizeEmployees(list []Employee) ([]Employee, error) {
+	seen := make(map[string]bool, len(list))
+	out := make([]Employee, 0, len(list))
+	for _, e := range list {
+		e.Email = strings.TrimSpace(e.Email)
+		if e.Email == "" || e.Name == "" {
+			return nil, fmt.Errorf("employee email and name are required (email %q)", e.Email)
+		}
+		key := strings.ToLower(e.Email)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate employee %s", e.Email)
+		}
+		seen[key] = true
+		if e.Status == "" {
+			e.Status = EmployeeActive
+		}
+		e.Department = strings.Join(SplitDepartmentPath(e.Department), "/")
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func checkThresholds(t HRSyncThresholds, active, creates, updates, freezes, unfreezes int) error {
+	ratio := func(n int) float64 {
+		if active == 0 {
+			return 0
+		}
+		return float64(n) / float64(active)
+	}
+	switch {
+	case t.MaxFreezeCount > 0 && freezes > t.MaxFreezeCount:
+		return fmt.Errorf("%w: %d accounts would be frozen, limit %d", ErrThresholdExceeded, freezes, t.MaxFreezeCount)
+	case t.MaxFreezeRatio > 0 && freezes > 0 && (active == 0 || ratio(freezes) > t.MaxFreezeRatio):
+		return fmt.Errorf("%w: %d of %d active accounts (%.1f%%) would be frozen, limit %.1f%%",
+			ErrThresholdExceeded, freezes, active, ratio(freezes)*100, t.MaxFreezeRatio*100)
+	case t.MaxCreateCount > 0 && creates > t.MaxCreateCount:
+		return fmt.Errorf("%w: %d accounts would be created, limit %d", ErrThresholdExceeded, creates, t.MaxCreateCount)
+	case t.MaxUpdateRatio > 0 && updates > 0 && (active == 0 || ratio(updates) > t.MaxUpdateRatio):
+		return fmt.Errorf("%w: %d of %d active accounts (%.1f%%) would be updated, limit %.1f%%",
+			ErrThresholdExceeded, updates, active, ratio(updates)*100, t.MaxUpdateRatio*100)
+	case t.MaxUnfreezeCount > 0 && unfreezes > t.MaxUnfreezeCount:
+		return fmt.Errorf("%w: %d accounts would be unfrozen, limit %d", ErrThresholdExceeded, unfreezes, t.MaxUnfreezeCount)
+	}
+	return nil
+}
+
+func (u *UserService) freezeChange(email, reason string) hrChange {
+	return hrChange{
+		action: HRSyncFreeze,
+		email:  email,
+		reason: reason,
+		apply: func(ctx context.Context) error {
+			return u.Freeze(ctx, UserLifecycleReq{BaseUserReq: BaseUserReq{Email: email}, Reason: reason})
+		},
+	}
+}
+
+func (u *UserService) unfreezeChange(email, reason string) hrChange {
+	return hrChange{
+		action: HRSyncUnfreeze,
+		email:  email,
+		reason: reason,
+		apply: func(ctx context.Context) error {
+			return u.Unfreeze(ctx, UserLifecycleReq{BaseUserReq: BaseUserReq{Email: email}, Reason: reason})
+		},
+	}
+}
+
+func (u *UserService) createChange(e Employee) hrChange {
+	var diffs []FieldDiff
+	diffs = diffString(diffs, "name", "", e.Name)
+	diffs = diffString(diffs, "employeeNo", "", e.EmployeeNo)
+	diffs = diffString(diffs, "department", "", e.Department)
+	diffs = diffString(diffs, "jobTitle", "", e.JobTitle)
+	return hrChange{
+		action: HRSyncCreate,
+		email:  e.Email,
+		diffs:  diffs,
+		apply: func(ctx context.Context) error {
+			deptID, err := u.Department.EnsurePath(ctx, e.Department)
+			if err != nil {
+				return err
+			}
+			password, err := u.PasswordPolicy().Generate(PasswordContext{Name: e.Name, Email: e.Email})
+			if err != nil {
+				return err
+			}
+			_, err = u.Create(ctx, CreateUserReq{
+				Email:                         e.Email,
+				Password:                      password,
+				Name:                          e.Name,
+				EmployeeNo:                    e.EmployeeNo,
+				JobTitle:                      e.JobTitle,
+				DepartmentIds:                 []string{deptID},
+				ForceChangePasswordNextSignIn: true,
+			})
+			return err
+		},
+	}
+}
+
+// updateChange 对比员工信息与帐号，部门以名单中的部门替换帐号的全部部门。
+// 名单中为空的字段视为没有提供，不参与对比，避免缺少的列清空帐号信息或把帐号移到根部门
+func (u *UserService) updateChange(tree *OrgTree, user User, e Employee) (hrChange, bool) {
+	var (
+		diffs []FieldDiff
+		patch = PatchUserReq{BaseUserReq: BaseUserReq{Email: user.Email}}
+	)
+	str := func(field, old, new string, dst **string) {
+		if d := diffString(nil, field, old, new); len(d) > 0 {
+			diffs = append(diffs, d...)
+			*dst = Ptr(new)
+		}
+	}
+	str("name", user.Name, e.Name, &patch.Name)
+	str("employeeNo", user.EmployeeNo, e.EmployeeNo, &patch.EmployeeNo)
+	str("jobTitle", user.JobTitle, e.JobTitle, &patch.JobTitle)
+	if manager := userManagerEmail(user); e.Manager != "" && !strings.EqualFold(manager, e.Manager) {
+		diffs = diffString(diffs, "manager", manager, e.Manager)
+		patch.ManagerEmail = Ptr(e.Manager)
+	}
+	var current []string
+	for _, id := range user.DepartmentIds {
+		if n := tree.Get(id); n != nil {
+			current = append(current, strings.Join(n.Path(), "/"))
+		} else {
+			current = append(current, id)
+		}
+	}
+	moved := e.Department != "" && (len(current) != 1 || current[0] != e.Department)
+	if moved {
+		diffs = append(diffs, FieldDiff{Field: "department", Old: strings.Join(current, ","), New: e.Department})
+	}
+	if len(diffs) == 0 {
+		return hrChange{}, false
+	}
+	return hrChange{
+		action: HRSyncUpdate,
+		email:  user.Email,
+		diffs:  diffs,
+		apply: func(ctx context.Context) error {
+			if moved {
+				deptID, err := u.Department.EnsurePath(ctx, e.Department)
+				if err != nil {
+					return err
+				}
+				patch.DepartmentIds = &[]string{deptID}
+			}
+			_, err := u.Patch(ctx, patch)
+			return err
+		},
+	}, true
+}
+
+// newRunID 生成本次同步的标识，写入每条日志
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
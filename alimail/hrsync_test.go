@@ -0,0 +1,130 @@
+package alimail
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hrDirectory 在 reorgDirectory 的部门树上提供用户列表：u1、u2 正常，u3 已冻结；
+// 与真实接口一样，u1 的上级只通过 managerInfo 返回
+func hrDirectory(req recordedRequest) (int, any) {
+	switch {
+	case req.Method == MethodGet && req.Path == "/v2/users":
+		rsp := ListUsersRsp{Users: []User{}, Total: 3}
+		if req.Query.Get("offset") == "0" {
+			u1 := User{ID: "u1", Email: "u1@example.com", Name: "张三", EmployeeNo: "E1", JobTitle: "工程师", DepartmentIds: []string{"A1"}, Status: NORMAL}
+			u1.ManagerInfo.Email = "m1@example.com"
+			rsp.Users = []User{
+				u1,
+				{ID: "u2", Email: "u2@example.com", Name: "李四", DepartmentIds: []string{"B"}, Status: NORMAL},
+				{ID: "u3", Email: "u3@example.com", Name: "王五", DepartmentIds: []string{"B"}, Status: FREEZE},
+			}
+		}
+		return http.StatusOK, rsp
+	case req.Method == MethodPost && req.Path == "/v2/users":
+		return http.StatusOK, User{ID: "n1", Email: "n1@example.com"}
+	case req.Method == MethodPatch && strings.HasPrefix(req.Path, "/v2/users/"):
+		return http.StatusOK, User{}
+	}
+	return 0, nil
+}
+
+func TestReadEmployeesCSVMissingColumns(t *testing.T) {
+	list, err := ReadEmployeesCSV(strings.NewReader("Email,Name\nu1@example.com,张三丰\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Employee{{Email: "u1@example.com", Name: "张三丰", Status: EmployeeActive}}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("employees = %+v", list)
+	}
+}
+
+func TestSyncEmployees(t *testing.T) {
+	// 名单只有 email、name、status、manager 四列，u1 的上级没有变化
+	employees, err := ReadEmployeesCSV(strings.NewReader(strings.Join([]string{
+		"email,name,status,manager",
+		"u1@example.com,张三丰,在职,M1@example.com",
+		"u3@example.com,王五,在职,",
+		"n1@example.com,新人,在职,",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, requests := newFakeServer(t, hrDirectory, reorgDirectory)
+	ctx := context.Background()
+
+	// 默认上限下冻结 u2 占正常帐号的 50%，中止且不做修改
+	if _, err := c.User.SyncEmployees(ctx, HRSyncReq{Employees: employees}); !errors.Is(err, ErrThresholdExceeded) {
+		t.Fatalf("err = %v, want ErrThresholdExceeded", err)
+	}
+	if w := writes(requests()); len(w) != 0 {
+		t.Fatalf("aborted sync sent %d writes", len(w))
+	}
+
+	report, err := c.User.SyncEmployees(ctx, HRSyncReq{Employees: employees, Thresholds: &HRSyncThresholds{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 1 || report.Updated != 1 || report.Frozen != 1 || report.Revived != 1 || report.Failed != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	var got []string
+	for _, r := range writes(requests()) {
+		got = append(got, r.Method+" "+r.Path)
+	}
+	want := []string{
+		"POST /v2/users",
+		"PATCH /v2/users/u3@example.com",
+		"PATCH /v2/users/u1@example.com",
+		"PATCH /v2/users/u2@example.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("writes = %q, want %q", got, want)
+	}
+	reasons := map[string]string{}
+	for _, e := range report.Entries {
+		if e.Reason != "" {
+			reasons[string(e.Action)+" "+e.Email] = e.Reason
+		}
+	}
+	wantReasons := map[string]string{
+		"unfreeze u3@example.com": "rehired (hr sync)",
+		"freeze u2@example.com":   "left company (hr sync)",
+	}
+	if !reflect.DeepEqual(reasons, wantReasons) {
+		t.Errorf("reasons = %v, want %v", reasons, wantReasons)
+	}
+	// 名单中没有的员工编号、职位与部门不会被清空或移到根部门
+	update := writes(requests())[2]
+	if want := map[string]any{"name": "张三丰"}; !reflect.DeepEqual(update.Body, want) {
+		t.Errorf("update body = %v, want %v", update.Body, want)
+	}
+	if ids := writes(requests())[0].Body["departmentIds"]; !reflect.DeepEqual(ids, []any{RootDepartmentID}) {
+		t.Errorf("new employee departments = %v", ids)
+	}
+}
+
+func TestDefaultHRSyncThresholdsLimitUpdates(t *testing.T) {
+	err := checkThresholds(DefaultHRSyncThresholds, 10, 0, 3, 0, 0)
+	if !errors.Is(err, ErrThresholdExceeded) {
+		t.Errorf("err = %v, want ErrThresholdExceeded", err)
+	}
+	if err := checkThresholds(DefaultHRSyncThresholds, 10, 0, 2, 0, 0); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestHRSyncThresholdsLimitUnfreezes(t *testing.T) {
+	limit := HRSyncThresholds{MaxUnfreezeCount: 1}
+	if err := checkThresholds(limit, 10, 0, 0, 0, 2); !errors.Is(err, ErrThresholdExceeded) {
+		t.Errorf("err = %v, want ErrThresholdExceeded", err)
+	}
+	if err := checkThresholds(limit, 10, 0, 0, 0, 1); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,1078 @@
+package alimail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) 与日程之间的互相转换
+
+const (
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405"
+	icsProdID         = "-//eryajf//go-alimail//CN"
+)
+
+// windowsZones Exchange/Outlook 导出的 ics 常用 Windows 时区名到 IANA 时区名的映射
+var windowsZones = map[string]string{
+	"China Standard Time":            "Asia/Shanghai",
+	"Taipei Standard Time":           "Asia/Taipei",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"Korea Standard Time":            "Asia/Seoul",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"India Standard Time":            "Asia/Kolkata",
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+	"Arabian Standard Time":          "Asia/Dubai",
+	"SE Asia Standard Time":          "Asia/Bangkok",
+	"E. South America Standard Time": "America/Sao_Paulo",
+}
+
+// loadLocation 加载时区，兼容 Windows 时区名
+func loadLocation(name string) (*time.Location, error) {
+	if iana, ok := windowsZones[name]; ok {
+		name = iana
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// WriteICS 将日程导出为一个 VCALENDAR，使用到的时区会生成对应的 VTIMEZONE
+func WriteICS(w io.Writer, events []CalendarEvent) error {
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:" + icsProdID)
+	iw.line("CALSCALE:GREGORIAN")
+
+	zones, err := collectZones(events)
+	if err != nil {
+		return err
+	}
+	for _, z := range zones {
+		z.write(iw)
+	}
+	stamp := time.Now().UTC().Format(icsDateTimeLayout) + "Z"
+	for _, e := range events {
+		if err := writeEvent(iw, e, stamp); err != nil {
+			return fmt.Errorf("event %q: %w", e.Summary, err)
+		}
+	}
+	iw.line("END:VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line 写入一行内容，超过 75 字节时按 RFC 5545 折行，且不拆分 UTF-8 字符
+func (iw *icsWriter) line(s string) {
+	if iw.err != nil {
+		return
+	}
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		if _, iw.err = iw.w.WriteString(s[:cut] + "\r\n "); iw.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = 74
+	}
+	_, iw.err = iw.w.WriteString(s + "\r\n")
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
+
+func icsEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+func icsUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func icsParamValue(s string) string {
+	if strings.ContainsAny(s, `;:,`) {
+		return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+	}
+	return s
+}
+
+type icsZone struct {
+	loc        *time.Location
+	start, end time.Time
+}
+
+// collectZones 收集日程用到的时区以及覆盖的时间范围
+func collectZones(events []CalendarEvent) ([]icsZone, error) {
+	zones := map[string]*icsZone{}
+	for _, e := range events {
+		if e.IsAllDay {
+			continue
+		}
+		for _, dt := range []EventDateTime{e.Start, e.End} {
+			if dt.TimeZone == "" || dt.TimeZone == "UTC" {
+				continue
+			}
+			t, err := dt.Time()
+			if err != nil {
+				return nil, fmt.Errorf("event %q: %w", e.Summary, err)
+			}
+			z, ok := zones[dt.TimeZone]
+			if !ok {
+				zones[dt.TimeZone] = &icsZone{loc: t.Location(), start: t, end: t}
+				continue
+			}
+			if t.Before(z.start) {
+				z.start = t
+			}
+			if t.After(z.end) {
+				z.end = t
+			}
+		}
+		if e.Recurrence != nil && e.Recurrence.Range.EndDate != "" {
+			if z, ok := zones[e.Start.TimeZone]; ok {
+				if t, err := time.ParseInLocation("2006-01-02", e.Recurrence.Range.EndDate, z.loc); err == nil && t.After(z.end) {
+					z.end = t
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]icsZone, 0, len(names))
+	for _, name := range names {
+		list = append(list, *zones[name])
+	}
+	return list, nil
+}
+
+// write 输出 VTIMEZONE，列出覆盖范围内每一次时区切换
+func (z icsZone) write(iw *icsWriter) {
+	iw.line("BEGIN:VTIMEZONE")
+	iw.line("TZID:" + z.loc.String())
+
+	from := time.Date(z.start.Year(), 1, 1, 0, 0, 0, 0, z.loc)
+	until := time.Date(z.end.Year()+1, 1, 1, 0, 0, 0, 0, z.loc)
+	t := from
+	for {
+		zs, ze := t.ZoneBounds()
+		name, offset := t.Zone()
+		_, prevOffset := t.Add(-time.Second).Zone()
+		begin := zs
+		if begin.IsZero() || begin.Before(from) {
+			begin = from
+			prevOffset = offset
+		}
+		kind := "STANDARD"
+		if t.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		iw.line("BEGIN:" + kind)
+		// DTSTART 为切换前的本地时间
+		iw.line("DTSTART:" + begin.UTC().Add(time.Duration(prevOffset)*time.Second).Format(icsDateTimeLayout))
+		iw.line("TZOFFSETFROM:" + formatOffset(prevOffset))
+		iw.line("TZOFFSETTO:" + formatOffset(offset))
+		iw.line("TZNAME:" + name)
+		iw.line("END:" + kind)
+		if ze.IsZero() || !ze.Before(until) {
+			break
+		}
+		t = ze
+	}
+	iw.line("END:VTIMEZONE")
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+func parseOffset(s string) (int, error) {
+	if len(s) < 5 || (s[0] != '+' && s[0] != '-') {
+		return 0, fmt.Errorf("invalid utc offset %q", s)
+	}
+	h, err := strconv.Atoi(s[1:3])
+	if err != nil {
+		return 0, fmt.Errorf("invalid utc offset %q", s)
+	}
+	m, err := strconv.Atoi(s[3:5])
+	if err != nil {
+		return 0, fmt.Errorf("invalid utc offset %q", s)
+	}
+	sec := h*3600 + m*60
+	if len(s) >= 7 {
+		if v, err := strconv.Atoi(s[5:7]); err == nil {
+			sec += v
+		}
+	}
+	if s[0] == '-' {
+		sec = -sec
+	}
+	return sec, nil
+}
+
+// icsTimeProp 生成 DTSTART/DTEND/EXDATE 的参数与取值
+func icsTimeProp(name string, dt EventDateTime, allDay bool) (string, error) {
+	t, err := dt.Time()
+	if err != nil {
+		return "", err
+	}
+	if allDay {
+		return name + ";VALUE=DATE:" + t.Format(icsDateLayout), nil
+	}
+	if dt.TimeZone == "" || dt.TimeZone == "UTC" {
+		return name + ":" + t.UTC().Format(icsDateTimeLayout) + "Z", nil
+	}
+	return name + ";TZID=" + icsParamValue(t.Location().String()) + ":" + t.Format(icsDateTimeLayout), nil
+}
+
+func writeEvent(iw *icsWriter, e CalendarEvent, stamp string) error {
+	iw.line("BEGIN:VEVENT")
+	uid := e.UID
+	if uid == "" {
+		uid = e.ID + "@alimail"
+	}
+	iw.line("UID:" + icsEscape(uid))
+	iw.line("DTSTAMP:" + stamp)
+
+	start, err := icsTimeProp("DTSTART", e.Start, e.IsAllDay)
+	if err != nil {
+		return err
+	}
+	iw.line(start)
+	if e.End.DateTime != "" {
+		end, err := icsTimeProp("DTEND", e.End, e.IsAllDay)
+		if err != nil {
+			return err
+		}
+		iw.line(end)
+	}
+	iw.line("SUMMARY:" + icsEscape(e.Summary))
+	if e.Description != "" {
+		iw.line("DESCRIPTION:" + icsEscape(e.Description))
+	}
+	if e.Location != "" {
+		iw.line("LOCATION:" + icsEscape(e.Location))
+	}
+	if e.Organizer != nil && e.Organizer.Email != "" {
+		prop := "ORGANIZER"
+		if e.Organizer.Name != "" {
+			prop += ";CN=" + icsParamValue(e.Organizer.Name)
+		}
+		iw.line(prop + ":mailto:" + e.Organizer.Email)
+	}
+	for _, a := range e.Attendees {
+		iw.line(attendeeProp(a))
+	}
+	switch e.ShowAs {
+	case "free":
+		iw.line("TRANSP:TRANSPARENT")
+	case "tentative":
+		iw.line("STATUS:TENTATIVE")
+		iw.line("TRANSP:OPAQUE")
+	default:
+		iw.line("TRANSP:OPAQUE")
+	}
+	if e.Recurrence != nil {
+		rule, err := recurrenceToRRule(e.Recurrence, e)
+		if err != nil {
+			return err
+		}
+		iw.line("RRULE:" + rule)
+		for _, d := range e.Recurrence.ExceptionDates {
+			ex, err := exceptionDateProp(d, e)
+			if err != nil {
+				return err
+			}
+			iw.line(ex)
+		}
+	}
+	if e.IsReminderOn {
+		iw.line("BEGIN:VALARM")
+		iw.line("ACTION:DISPLAY")
+		iw.line("DESCRIPTION:" + icsEscape(e.Summary))
+		iw.line(fmt.Sprintf("TRIGGER:-PT%dM", e.ReminderMinutesBeforeStart))
+		iw.line("END:VALARM")
+	}
+	iw.line("END:VEVENT")
+	return nil
+}
+
+func attendeeProp(a Attendee) string {
+	prop := "ATTENDEE"
+	if a.Name != "" {
+		prop += ";CN=" + icsParamValue(a.Name)
+	}
+	switch a.Type {
+	case "optional":
+		prop += ";ROLE=OPT-PARTICIPANT"
+	case "resource":
+		prop += ";CUTYPE=RESOURCE;ROLE=NON-PARTICIPANT"
+	default:
+		prop += ";ROLE=REQ-PARTICIPANT"
+	}
+	switch a.ResponseStatus {
+	case "accepted":
+		prop += ";PARTSTAT=ACCEPTED"
+	case "declined":
+		prop += ";PARTSTAT=DECLINED"
+	case "tentative":
+		prop += ";PARTSTAT=TENTATIVE"
+	default:
+		prop += ";PARTSTAT=NEEDS-ACTION"
+	}
+	return prop + ":mailto:" + a.Email
+}
+
+// exceptionDateProp 排除日期沿用主日程开始时间的时分秒
+func exceptionDateProp(date string, e CalendarEvent) (string, error) {
+	if e.IsAllDay {
+		d, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return "", fmt.Errorf("invalid exception date %q", date)
+		}
+		return "EXDATE;VALUE=DATE:" + d.Format(icsDateLayout), nil
+	}
+	clock := "00:00:00"
+	if i := strings.IndexByte(e.Start.DateTime, 'T'); i >= 0 {
+		clock = e.Start.DateTime[i+1:]
+	}
+	return icsTimeProp("EXDATE", EventDateTime{DateTime: date + "T" + clock, TimeZone: e.Start.TimeZone}, false)
+}
+
+var (
+	weekdayToICS = map[string]string{
+		"sunday": "SU", "monday": "MO", "tuesday": "TU", "wednesday": "WE",
+		"thursday": "TH", "friday": "FR", "saturday": "SA",
+	}
+	indexToICS = map[string]string{
+		"first": "1", "second": "2", "third": "3", "fourth": "4", "last": "-1",
+	}
+)
+
+func recurrenceToRRule(r *Recurrence, e CalendarEvent) (string, error) {
+	p := r.Pattern
+	var parts []string
+	switch p.Type {
+	case RecurrenceDaily:
+		parts = append(parts, "FREQ=DAILY")
+	case RecurrenceWeekly:
+		parts = append(parts, "FREQ=WEEKLY")
+	case RecurrenceAbsoluteMonthly, RecurrenceRelativeMonthly:
+		parts = append(parts, "FREQ=MONTHLY")
+	case RecurrenceAbsoluteYearly:
+		parts = append(parts, "FREQ=YEARLY")
+	default:
+		return "", fmt.Errorf("unsupported recurrence type %q", p.Type)
+	}
+	if p.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(p.Interval))
+	}
+	if len(p.DaysOfWeek) > 0 {
+		prefix := ""
+		if p.Type == RecurrenceRelativeMonthly {
+			prefix = indexToICS[p.Index]
+		}
+		days := make([]string, 0, len(p.DaysOfWeek))
+		for _, d := range p.DaysOfWeek {
+			code, ok := weekdayToICS[strings.ToLower(d)]
+			if !ok {
+				return "", fmt.Errorf("invalid day of week %q", d)
+			}
+			days = append(days, prefix+code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if p.DayOfMonth > 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(p.DayOfMonth))
+	}
+	if p.Month > 0 {
+		parts = append(parts, "BYMONTH="+strconv.Itoa(p.Month))
+	}
+	switch r.Range.Type {
+	case RecurrenceRangeNumbered:
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Range.NumberOfOccurrences))
+	case RecurrenceRangeEndDate:
+		loc := time.UTC
+		if e.Start.TimeZone != "" {
+			l, err := loadLocation(e.Start.TimeZone)
+			if err != nil {
+				return "", err
+			}
+			loc = l
+		}
+		end, err := time.ParseInLocation("2006-01-02", r.Range.EndDate, loc)
+		if err != nil {
+			return "", fmt.Errorf("invalid recurrence end date %q", r.Range.EndDate)
+		}
+		if e.IsAllDay {
+			parts = append(parts, "UNTIL="+end.Format(icsDateLayout))
+		} else {
+			until := end.Add(24*time.Hour - time.Second).UTC()
+			parts = append(parts, "UNTIL="+until.Format(icsDateTimeLayout)+"Z")
+		}
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// icsProp 解析后的一行内容
+type icsProp struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// readICSLines 读取并展开折行
+func readICSLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		l := strings.TrimRight(scanner.Text(), "\r")
+		if l == "" {
+			continue
+		}
+		if (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, scanner.Err()
+}
+
+func parseICSProp(line string) (icsProp, error) {
+	p := icsProp{Params: map[string]string{}}
+	inQuote := false
+	colon := -1
+	for i := 0; i < len(line); i++ {
+		if line[i] == '"' {
+			inQuote = !inQuote
+		} else if line[i] == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return p, fmt.Errorf("invalid content line %q", line)
+	}
+	p.Value = line[colon+1:]
+	head := line[:colon]
+	var fields []string
+	inQuote = false
+	last := 0
+	for i := 0; i < len(head); i++ {
+		if head[i] == '"' {
+			inQuote = !inQuote
+		} else if head[i] == ';' && !inQuote {
+			fields = append(fields, head[last:i])
+			last = i + 1
+		}
+	}
+	fields = append(fields, head[last:])
+	p.Name = strings.ToUpper(fields[0])
+	for _, f := range fields[1:] {
+		k, v, _ := strings.Cut(f, "=")
+		p.Params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p, nil
+}
+
+// ParseICS 解析 ics 内容中的全部 VEVENT，属性的先后顺序不影响结果。
+// 带 RECURRENCE-ID 的例外实例会合并到同一 UID 的主日程：主日程排除该次重复，
+// 修改过的实例作为独立日程返回，已取消（STATUS:CANCELLED）的实例不再返回。
+func ParseICS(r io.Reader) ([]CalendarEvent, error) {
+	lines, err := readICSLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ics: %w", err)
+	}
+
+	// 第一遍收集 VTIMEZONE，用于无法识别的 TZID
+	zones := map[string]*time.Location{}
+	var tzid string
+	var tzOffset *int
+	var stack []string
+	for _, l := range lines {
+		p, err := parseICSProp(l)
+		if err != nil {
+			return nil, err
+		}
+		switch p.Name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(p.Value))
+			if strings.EqualFold(p.Value, "VTIMEZONE") {
+				tzid, tzOffset = "", nil
+			}
+		case "END":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			if strings.EqualFold(p.Value, "VTIMEZONE") && tzid != "" {
+				if loc, err := loadLocation(tzid); err == nil {
+					zones[tzid] = loc
+				} else if tzOffset != nil {
+					zones[tzid] = time.FixedZone(tzid, *tzOffset)
+				}
+			}
+		case "TZID":
+			if len(stack) > 0 && stack[len(stack)-1] == "VTIMEZONE" {
+				tzid = p.Value
+			}
+		case "TZOFFSETTO":
+			if len(stack) > 0 && stack[len(stack)-1] == "STANDARD" && tzOffset == nil {
+				if off, err := parseOffset(p.Value); err == nil {
+					tzOffset = &off
+				}
+			}
+		}
+	}
+
+	// 第二遍先收集每个 VEVENT 的全部属性，DTSTART 可能出现在 RRULE、DURATION、EXDATE 之后
+	var (
+		vevents []icsEvent
+		cur     *icsEvent
+		inAlarm bool
+	)
+	for _, l := range lines {
+		p, _ := parseICSProp(l)
+		switch {
+		case p.Name == "BEGIN" && strings.EqualFold(p.Value, "VEVENT"):
+			cur = &icsEvent{}
+		case p.Name == "END" && strings.EqualFold(p.Value, "VEVENT"):
+			if cur != nil {
+				vevents = append(vevents, *cur)
+			}
+			cur = nil
+		case p.Name == "BEGIN" && strings.EqualFold(p.Value, "VALARM"):
+			inAlarm = true
+		case p.Name == "END" && strings.EqualFold(p.Value, "VALARM"):
+			inAlarm = false
+		case cur == nil:
+		case inAlarm:
+			if p.Name == "TRIGGER" && p.Params["VALUE"] != "DATE-TIME" {
+				cur.triggers = append(cur.triggers, p)
+			}
+		default:
+			cur.props = append(cur.props, p)
+		}
+	}
+
+	events := make([]CalendarEvent, 0, len(vevents))
+	var overrides []icsOverride
+	for _, ve := range vevents {
+		e, o, err := ve.resolve(zones)
+		if err != nil {
+			return nil, err
+		}
+		if o != nil {
+			o.event = e
+			overrides = append(overrides, *o)
+			continue
+		}
+		events = append(events, e)
+	}
+	return mergeOverrides(events, overrides), nil
+}
+
+// icsEvent 一个 VEVENT 中收集到的属性
+type icsEvent struct {
+	props    []icsProp
+	triggers []icsProp // VALARM 中相对开始时间的 TRIGGER
+}
+
+// icsOverride 带 RECURRENCE-ID 的例外实例
+type icsOverride struct {
+	event     CalendarEvent
+	date      string // 被替换的那次重复的日期 2006-01-02
+	cancelled bool
+}
+
+// resolve 在已知 DTSTART 的前提下解析全部属性，例外实例同时返回其 RECURRENCE-ID
+func (ve icsEvent) resolve(zones map[string]*time.Location) (CalendarEvent, *icsOverride, error) {
+	var (
+		e       CalendarEvent
+		dtstart time.Time
+	)
+	for _, p := range ve.props {
+		if p.Name != "DTSTART" {
+			continue
+		}
+		t, allDay, err := parseICSTime(p, zones)
+		if err != nil {
+			return e, nil, err
+		}
+		dtstart = t
+		e.IsAllDay = allDay
+		e.Start = NewEventDateTime(t)
+	}
+
+	var (
+		rrule    *Recurrence
+		exdates  []string
+		override *icsOverride
+	)
+	for _, p := range ve.props {
+		switch p.Name {
+		case "UID":
+			e.UID = icsUnescape(p.Value)
+		case "SUMMARY":
+			e.Summary = icsUnescape(p.Value)
+		case "DESCRIPTION":
+			e.Description = icsUnescape(p.Value)
+		case "LOCATION":
+			e.Location = icsUnescape(p.Value)
+		case "DTEND":
+			t, _, err := parseICSTime(p, zones)
+			if err != nil {
+				return e, nil, err
+			}
+			e.End = NewEventDateTime(t)
+		case "DURATION":
+			d, err := parseICSDuration(p.Value)
+			if err != nil {
+				return e, nil, err
+			}
+			if !dtstart.IsZero() {
+				e.End = NewEventDateTime(dtstart.Add(d))
+			}
+		case "ORGANIZER":
+			a := parseAttendee(p)
+			e.Organizer = &a
+		case "ATTENDEE":
+			e.Attendees = append(e.Attendees, parseAttendee(p))
+		case "TRANSP":
+			if strings.EqualFold(p.Value, "TRANSPARENT") {
+				e.ShowAs = "free"
+			} else if e.ShowAs == "" {
+				e.ShowAs = "busy"
+			}
+		case "STATUS":
+			switch {
+			case strings.EqualFold(p.Value, "TENTATIVE"):
+				e.ShowAs = "tentative"
+			case strings.EqualFold(p.Value, "CANCELLED"):
+				if override == nil {
+					override = &icsOverride{}
+				}
+				override.cancelled = true
+			}
+		case "RRULE":
+			rec, err := parseRRule(p.Value, dtstart)
+			if err != nil {
+				return e, nil, err
+			}
+			rrule = rec
+		case "EXDATE":
+			dates, err := icsDates(p, zones, dtstart)
+			if err != nil {
+				return e, nil, err
+			}
+			exdates = append(exdates, dates...)
+		case "RECURRENCE-ID":
+			dates, err := icsDates(p, zones, dtstart)
+			if err != nil {
+				return e, nil, err
+			}
+			if override == nil {
+				override = &icsOverride{}
+			}
+			override.date = dates[0]
+		}
+	}
+	for _, p := range ve.triggers {
+		minutes, err := parseTriggerMinutes(p.Value)
+		if err != nil {
+			return e, nil, err
+		}
+		e.IsReminderOn = true
+		e.ReminderMinutesBeforeStart = minutes
+	}
+	// 只有 EXDATE 没有 RRULE 的日程，EXDATE 没有意义
+	if rrule != nil {
+		rrule.ExceptionDates = exdates
+		e.Recurrence = rrule
+	}
+	if e.End.DateTime == "" {
+		e.End = e.Start
+	}
+	if override != nil && override.date == "" {
+		// 没有 RECURRENCE-ID 的 STATUS:CANCELLED 只是普通的已取消日程
+		override = nil
+	}
+	return e, override, nil
+}
+
+// icsDates 解析 EXDATE、RECURRENCE-ID 中的日期，换算到主日程开始时间的时区
+func icsDates(p icsProp, zones map[string]*time.Location, dtstart time.Time) ([]string, error) {
+	var dates []string
+	for _, v := range strings.Split(p.Value, ",") {
+		t, _, err := parseICSTime(icsProp{Name: p.Name, Params: p.Params, Value: v}, zones)
+		if err != nil {
+			return nil, err
+		}
+		if !dtstart.IsZero() {
+			t = t.In(dtstart.Location())
+		}
+		dates = append(dates, t.Format("2006-01-02"))
+	}
+	return dates, nil
+}
+
+// mergeOverrides 将例外实例合并到同一 UID 的主日程，找不到主日程的例外实例按独立日程返回
+func mergeOverrides(events []CalendarEvent, overrides []icsOverride) []CalendarEvent {
+	for _, o := range overrides {
+		i := slices.IndexFunc(events, func(e CalendarEvent) bool {
+			return e.UID != "" && e.UID == o.event.UID && e.Recurrence != nil
+		})
+		if i >= 0 {
+			if r := events[i].Recurrence; !slices.Contains(r.ExceptionDates, o.date) {
+				r.ExceptionDates = append(r.ExceptionDates, o.date)
+			}
+		}
+		if o.cancelled {
+			continue
+		}
+		// 例外实例本身只发生一次，不能带上主日程的重复规则
+		o.event.Recurrence = nil
+		events = append(events, o.event)
+	}
+	return events
+}
+
+// parseICSTime 解析 DATE 或 DATE-TIME，返回值是否为全天
+func parseICSTime(p icsProp, zones map[string]*time.Location) (time.Time, bool, error) {
+	v := p.Value
+	if p.Params["VALUE"] == "DATE" || len(v) == len(icsDateLayout) {
+		t, err := time.ParseInLocation(icsDateLayout, v, time.UTC)
+		if err != nil {
+			return t, true, fmt.Errorf("invalid %s %q", p.Name, v)
+		}
+		return t, true, nil
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.ParseInLocation(icsDateTimeLayout, strings.TrimSuffix(v, "Z"), time.UTC)
+		if err != nil {
+			return t, false, fmt.Errorf("invalid %s %q", p.Name, v)
+		}
+		return t, false, nil
+	}
+	loc := time.UTC
+	if tz := p.Params["TZID"]; tz != "" {
+		if l, ok := zones[tz]; ok {
+			loc = l
+		} else if l, err := loadLocation(tz); err == nil {
+			loc = l
+		} else {
+			return time.Time{}, false, fmt.Errorf("%s: %w", p.Name, err)
+		}
+	}
+	t, err := time.ParseInLocation(icsDateTimeLayout, v, loc)
+	if err != nil {
+		return t, false, fmt.Errorf("invalid %s %q", p.Name, v)
+	}
+	// 无法映射到 IANA 的时区统一换算成 UTC，保证 timeZone 可被服务端识别
+	if _, err := time.LoadLocation(loc.String()); err != nil {
+		t = t.UTC()
+	}
+	return t, false, nil
+}
+
+// parseICSDuration 解析 RFC 5545 DURATION，如 -PT15M、P1DT2H、P1W
+func parseICSDuration(s string) (time.Duration, error) {
+	orig := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	s = s[1:]
+	var d time.Duration
+	num := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T':
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+			num = ""
+			switch c {
+			case 'W':
+				d += time.Duration(n) * 7 * 24 * time.Hour
+			case 'D':
+				d += time.Duration(n) * 24 * time.Hour
+			case 'H':
+				d += time.Duration(n) * time.Hour
+			case 'M':
+				d += time.Duration(n) * time.Minute
+			case 'S':
+				d += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+		}
+	}
+	return sign * d, nil
+}
+
+// parseTriggerMinutes 将相对开始时间的 TRIGGER 转为提前提醒的分钟数
+func parseTriggerMinutes(v string) (int, error) {
+	d, err := parseICSDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d > 0 {
+		return 0, nil
+	}
+	return int(-d / time.Minute), nil
+}
+
+func parseAttendee(p icsProp) Attendee {
+	a := Attendee{Name: p.Params["CN"]}
+	email := p.Value
+	if len(email) >= 7 && strings.EqualFold(email[:7], "mailto:") {
+		email = email[7:]
+	}
+	a.Email = email
+	switch {
+	case p.Params["CUTYPE"] == "RESOURCE" || p.Params["CUTYPE"] == "ROOM":
+		a.Type = "resource"
+	case p.Params["ROLE"] == "OPT-PARTICIPANT":
+		a.Type = "optional"
+	default:
+		a.Type = "required"
+	}
+	switch p.Params["PARTSTAT"] {
+	case "ACCEPTED":
+		a.ResponseStatus = "accepted"
+	case "DECLINED":
+		a.ResponseStatus = "declined"
+	case "TENTATIVE":
+		a.ResponseStatus = "tentative"
+	default:
+		a.ResponseStatus = "none"
+	}
+	return a
+}
+
+// parseRRule 将 RRULE 转为日程重复规则，不支持的写法会返回错误而不是静默丢弃
+func parseRRule(v string, dtstart time.Time) (*Recurrence, error) {
+	rec := &Recurrence{}
+	rec.Pattern.Interval = 1
+	rec.Range.Type = RecurrenceRangeNoEnd
+	if !dtstart.IsZero() {
+		rec.Range.StartDate = dtstart.Format("2006-01-02")
+	}
+	var freq, byday string
+	for _, part := range strings.Split(v, ";") {
+		k, val, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE interval %q", val)
+			}
+			rec.Pattern.Interval = n
+		case "BYDAY":
+			byday = strings.ToUpper(val)
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported RRULE BYMONTHDAY %q", val)
+			}
+			rec.Pattern.DayOfMonth = n
+		case "BYMONTH":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported RRULE BYMONTH %q", val)
+			}
+			rec.Pattern.Month = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE count %q", val)
+			}
+			rec.Range.Type = RecurrenceRangeNumbered
+			rec.Range.NumberOfOccurrences = n
+		case "UNTIL":
+			t, _, err := parseICSTime(icsProp{Name: "UNTIL", Params: map[string]string{}, Value: val}, nil)
+			if err != nil {
+				return nil, err
+			}
+			if !dtstart.IsZero() {
+				t = t.In(dtstart.Location())
+			}
+			rec.Range.Type = RecurrenceRangeEndDate
+			rec.Range.EndDate = t.Format("2006-01-02")
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %q", part)
+		}
+	}
+
+	var index string
+	var days []string
+	if byday != "" {
+		for _, d := range strings.Split(byday, ",") {
+			code := d[len(d)-min(2, len(d)):]
+			prefix := d[:len(d)-len(code)]
+			name := ""
+			for k, v := range weekdayToICS {
+				if v == code {
+					name = k
+				}
+			}
+			if name == "" {
+				return nil, fmt.Errorf("invalid RRULE BYDAY %q", d)
+			}
+			if prefix != "" {
+				for k, v := range indexToICS {
+					if v == strings.TrimPrefix(prefix, "+") {
+						index = k
+					}
+				}
+				if index == "" {
+					return nil, fmt.Errorf("unsupported RRULE BYDAY %q", d)
+				}
+			}
+			days = append(days, name)
+		}
+	}
+
+	switch freq {
+	case "DAILY":
+		rec.Pattern.Type = RecurrenceDaily
+	case "WEEKLY":
+		rec.Pattern.Type = RecurrenceWeekly
+		if len(days) == 0 && !dtstart.IsZero() {
+			days = []string{strings.ToLower(dtstart.Weekday().String())}
+		}
+		rec.Pattern.DaysOfWeek = days
+	case "MONTHLY":
+		if len(days) > 0 {
+			if index == "" {
+				return nil, fmt.Errorf("unsupported RRULE %q", v)
+			}
+			rec.Pattern.Type = RecurrenceRelativeMonthly
+			rec.Pattern.DaysOfWeek = days
+			rec.Pattern.Index = index
+		} else {
+			rec.Pattern.Type = RecurrenceAbsoluteMonthly
+			if rec.Pattern.DayOfMonth == 0 && !dtstart.IsZero() {
+				rec.Pattern.DayOfMonth = dtstart.Day()
+			}
+		}
+	case "YEARLY":
+		if len(days) > 0 {
+			return nil, fmt.Errorf("unsupported RRULE %q", v)
+		}
+		rec.Pattern.Type = RecurrenceAbsoluteYearly
+		if !dtstart.IsZero() {
+			if rec.Pattern.Month == 0 {
+				rec.Pattern.Month = int(dtstart.Month())
+			}
+			if rec.Pattern.DayOfMonth == 0 {
+				rec.Pattern.DayOfMonth = dtstart.Day()
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported RRULE frequency %q", freq)
+	}
+	return rec, nil
+}
+
+// ExportICS 将日历中指定时间范围内的日程导出为单个 ics 文件
+func (c *CalendarService) ExportICS(ctx context.Context, req ListEventsReq, w io.Writer) error {
+	events, err := c.ListEvents(ctx, req)
+	if err != nil {
+		return err
+	}
+	return WriteICS(w, events)
+}
+
+// ImportEventResult 导入单个日程的结果
+type ImportEventResult struct {
+	Event   CalendarEvent  // ics 中解析出的日程
+	Created *CalendarEvent // 创建成功后服务端返回的日程
+	Err     error          // 创建失败的原因
+}
+
+// ImportICS 将 ics 中的日程逐个导入到指定日历，单个日程失败不会中断导入
+func (c *CalendarService) ImportICS(ctx context.Context, email, calendarID string, r io.Reader) ([]ImportEventResult, error) {
+	events, err := ParseICS(r)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ImportEventResult, 0, len(events))
+	for _, e := range events {
+		created, err := c.CreateEvent(ctx, email, calendarID, e)
+		results = append(results, ImportEventResult{Event: e, Created: created, Err: err})
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+	}
+	return results, nil
+}
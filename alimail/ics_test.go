@@ -0,0 +1,132 @@
+package alimail
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestICSRoundTrip(t *testing.T) {
+	weekly := &Recurrence{}
+	weekly.Pattern.Type = RecurrenceWeekly
+	weekly.Pattern.Interval = 2
+	weekly.Pattern.DaysOfWeek = []string{"monday", "wednesday"}
+	weekly.Range.Type = RecurrenceRangeEndDate
+	weekly.Range.StartDate = "2024-03-04"
+	weekly.Range.EndDate = "2024-06-30"
+	weekly.ExceptionDates = []string{"2024-03-18"}
+
+	events := []CalendarEvent{
+		{
+			UID:                        "weekly@example.com",
+			Summary:                    "周会; 同步, 进度",
+			Description:                "第一行\n第二行",
+			Location:                   "会议室 A",
+			Start:                      EventDateTime{DateTime: "2024-03-04T10:00:00", TimeZone: "Asia/Shanghai"},
+			End:                        EventDateTime{DateTime: "2024-03-04T11:00:00", TimeZone: "Asia/Shanghai"},
+			Organizer:                  &Attendee{Email: "boss@example.com", Name: "Boss", Type: "required", ResponseStatus: "none"},
+			Attendees:                  []Attendee{{Email: "a@example.com", Name: "A", Type: "optional", ResponseStatus: "accepted"}},
+			Recurrence:                 weekly,
+			IsReminderOn:               true,
+			ReminderMinutesBeforeStart: 15,
+			ShowAs:                     "busy",
+		},
+		{
+			UID:      "holiday@example.com",
+			Summary:  "休假",
+			Start:    EventDateTime{DateTime: "2024-05-01T00:00:00", TimeZone: "UTC"},
+			End:      EventDateTime{DateTime: "2024-05-02T00:00:00", TimeZone: "UTC"},
+			IsAllDay: true,
+			ShowAs:   "free",
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, events); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseICS(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, events) {
+		t.Errorf("round trip mismatch\n got %+v\nwant %+v", got, events)
+	}
+}
+
+func TestParseICSPropertyOrder(t *testing.T) {
+	// Outlook/Exchange 导出的 VEVENT 中 RRULE、DURATION、EXDATE 可能出现在 DTSTART 之前
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:order@example.com",
+		"RRULE:FREQ=MONTHLY;COUNT=3",
+		"EXDATE:20240415T020000Z",
+		"DURATION:PT30M",
+		"SUMMARY:月度复盘",
+		"DTSTART;TZID=China Standard Time:20240315T100000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	events, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events", len(events))
+	}
+	e := events[0]
+	if e.End != (EventDateTime{DateTime: "2024-03-15T10:30:00", TimeZone: "Asia/Shanghai"}) {
+		t.Errorf("end = %+v", e.End)
+	}
+	r := e.Recurrence
+	if r == nil || r.Pattern.Type != RecurrenceAbsoluteMonthly || r.Pattern.DayOfMonth != 15 || r.Range.StartDate != "2024-03-15" {
+		t.Fatalf("recurrence = %+v", r)
+	}
+	// 02:00Z 在 Asia/Shanghai 是 10:00，日期不会因 UTC 而偏移
+	if !reflect.DeepEqual(r.ExceptionDates, []string{"2024-04-15"}) {
+		t.Errorf("exception dates = %v", r.ExceptionDates)
+	}
+}
+
+func TestParseICSMergesOverrides(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"DTSTART:20240304T010000Z",
+		"DTEND:20240304T011500Z",
+		"RRULE:FREQ=DAILY;COUNT=5",
+		"SUMMARY:站会",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"RECURRENCE-ID:20240305T010000Z",
+		"DTSTART:20240305T030000Z",
+		"DTEND:20240305T031500Z",
+		"SUMMARY:站会（改期）",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"RECURRENCE-ID:20240306T010000Z",
+		"DTSTART:20240306T010000Z",
+		"STATUS:CANCELLED",
+		"SUMMARY:站会",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	events, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events: %+v", len(events), events)
+	}
+	master, moved := events[0], events[1]
+	if want := []string{"2024-03-05", "2024-03-06"}; !reflect.DeepEqual(master.Recurrence.ExceptionDates, want) {
+		t.Errorf("master exception dates = %v, want %v", master.Recurrence.ExceptionDates, want)
+	}
+	if moved.Summary != "站会（改期）" || moved.Recurrence != nil || moved.Start.DateTime != "2024-03-05T03:00:00" {
+		t.Errorf("override = %+v", moved)
+	}
+}
@@ -0,0 +1,254 @@
+package alimail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+)
+
+// 收信规则（邮件过滤器）
+
+type MailRuleMatch string
+
+// 多个条件之间的关系
+const (
+	MailRuleMatchAll MailRuleMatch = "all" // 满足所有条件
+	MailRuleMatchAny MailRuleMatch = "any" // 满足任一条件
+)
+
+// MailRuleConditions 收信规则的条件，为空的条件不参与匹配
+type MailRuleConditions struct {
+	FromContains    []string `json:"fromContains,omitempty"`    // 发件人包含
+	ToContains      []string `json:"toContains,omitempty"`      // 收件人（含抄送）包含
+	SubjectContains []string `json:"subjectContains,omitempty"` // 主题包含
+	SizeGreaterThan int64    `json:"sizeGreaterThan,omitempty"` // 邮件大小大于，字节
+	SizeLessThan    int64    `json:"sizeLessThan,omitempty"`    // 邮件大小小于，字节
+	HasAttachments  *bool    `json:"hasAttachments,omitempty"`  // 是否带附件
+}
+
+func (c MailRuleConditions) empty() bool {
+	return len(c.FromContains) == 0 && len(c.ToContains) == 0 && len(c.SubjectContains) == 0 &&
+		c.SizeGreaterThan == 0 && c.SizeLessThan == 0 && c.HasAttachments == nil
+}
+
+// MailRuleActions 收信规则命中后执行的动作
+type MailRuleActions struct {
+	MoveToFolder   string   `json:"moveToFolder,omitempty"`   // 移动到的文件夹ID
+	MarkAsRead     bool     `json:"markAsRead,omitempty"`     // 标记为已读
+	ForwardTo      []string `json:"forwardTo,omitempty"`      // 转发到
+	Delete         bool     `json:"delete,omitempty"`         // 删除
+	AddTags        []string `json:"addTags,omitempty"`        // 添加标签
+	StopProcessing bool     `json:"stopProcessing,omitempty"` // 不再执行后续规则
+}
+
+func (a MailRuleActions) empty() bool {
+	return a.MoveToFolder == "" && !a.MarkAsRead && len(a.ForwardTo) == 0 && !a.Delete && len(a.AddTags) == 0
+}
+
+// MailRule 收信规则
+type MailRule struct {
+	ID         string             `json:"id,omitempty"` // 规则ID
+	Name       string             `json:"name"`         // 规则名称
+	Enabled    bool               `json:"enabled"`      // 是否启用
+	Sequence   int                `json:"sequence"`     // 执行顺序，从 1 开始，越小越先执行
+	Match      MailRuleMatch      `json:"match"`        // 条件之间的关系
+	Conditions MailRuleConditions `json:"conditions"`   // 条件
+	Actions    MailRuleActions    `json:"actions"`      // 动作
+}
+
+// Validate 在本地校验规则，避免把无效规则提交到服务端
+func (r MailRule) Validate() error {
+	var errs []error
+	if r.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if r.Sequence < 0 {
+		errs = append(errs, errors.New("sequence can't be negative"))
+	}
+	switch r.Match {
+	case MailRuleMatchAll, MailRuleMatchAny:
+	default:
+		errs = append(errs, fmt.Errorf("invalid match %q", r.Match))
+	}
+
+	c := r.Conditions
+	if c.empty() {
+		errs = append(errs, errors.New("at least one condition is required"))
+	}
+	if c.SizeGreaterThan < 0 || c.SizeLessThan < 0 {
+		errs = append(errs, errors.New("size conditions can't be negative"))
+	}
+	if c.SizeGreaterThan > 0 && c.SizeLessThan > 0 && c.SizeGreaterThan >= c.SizeLessThan {
+		errs = append(errs, errors.New("sizeGreaterThan must be less than sizeLessThan"))
+	}
+	for _, list := range [][]string{c.FromContains, c.ToContains, c.SubjectContains} {
+		for _, v := range list {
+			if v == "" {
+				errs = append(errs, errors.New("condition values can't be empty"))
+			}
+		}
+	}
+
+	a := r.Actions
+	if a.empty() {
+		errs = append(errs, errors.New("at least one action is required"))
+	}
+	if a.Delete && (a.MoveToFolder != "" || len(a.ForwardTo) > 0 || a.MarkAsRead || len(a.AddTags) > 0) {
+		errs = append(errs, errors.New("delete can't be combined with other actions"))
+	}
+	for _, addr := range a.ForwardTo {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid forward address %q", addr))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid mail rule %q: %w", r.Name, err)
+	}
+	return nil
+}
+
+func rulesPath(email string) (string, error) {
+	if email == "" {
+		return "", fmt.Errorf("email is required")
+	}
+	return fmt.Sprintf("/v2/users/%s/mailFolders/messageRules", email), nil
+}
+
+type listMailRulesRsp struct {
+	Rules []MailRule `json:"rules"`
+}
+
+// ListRules 获取用户的全部收信规则
+func (m *MailFolderService) ListRules(ctx context.Context, email string) ([]MailRule, error) {
+	path, err := rulesPath(email)
+	if err != nil {
+		return nil, err
+	}
+	var dataObj listMailRulesRsp
+	if err := m.doJSON(ctx, MethodGet, path, nil, &dataObj); err != nil {
+		return nil, err
+	}
+	return dataObj.Rules, nil
+}
+
+// CreateRule 创建收信规则
+func (m *MailFolderService) CreateRule(ctx context.Context, email string, rule MailRule) (*MailRule, error) {
+	path, err := rulesPath(email)
+	if err != nil {
+		return nil, err
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	var dataObj MailRule
+	if err := m.doJSON(ctx, MethodPost, path, rule, &dataObj); err != nil {
+		return nil, err
+	}
+	return &dataObj, nil
+}
+
+// UpdateRule 更新收信规则
+func (m *MailFolderService) UpdateRule(ctx context.Context, email string, rule MailRule) error {
+	path, err := rulesPath(email)
+	if err != nil {
+		return err
+	}
+	if rule.ID == "" {
+		return fmt.Errorf("rule id is required")
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	return m.doJSON(ctx, MethodPatch, path+"/"+rule.ID, rule, nil)
+}
+
+// DeleteRule 删除收信规则
+func (m *MailFolderService) DeleteRule(ctx context.Context, email, id string) error {
+	path, err := rulesPath(email)
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		return fmt.Errorf("rule id is required")
+	}
+	return m.doJSON(ctx, MethodDelete, path+"/"+id, nil, nil)
+}
+
+// ReorderRules 按 ids 的顺序重新设置规则的执行顺序，ids 必须包含用户的全部规则
+func (m *MailFolderService) ReorderRules(ctx context.Context, email string, ids []string) error {
+	rules, err := m.ListRules(ctx, email)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]MailRule, len(rules))
+	for _, r := range rules {
+		byID[r.ID] = r
+	}
+	if len(ids) != len(rules) {
+		return fmt.Errorf("expected %d rule ids, got %d", len(rules), len(ids))
+	}
+	path, _ := rulesPath(email)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := byID[id]; !ok {
+			return fmt.Errorf("unknown rule id %s", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate rule id %s", id)
+		}
+		seen[id] = true
+	}
+	for i, id := range ids {
+		if byID[id].Sequence == i+1 {
+			continue
+		}
+		if err := m.doJSON(ctx, MethodPatch, path+"/"+id, map[string]int{"sequence": i + 1}, nil); err != nil {
+			return fmt.Errorf("reorder rule %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// EnsureRule 按名称创建或更新用户的收信规则，用于下发标准化规则
+func (m *MailFolderService) EnsureRule(ctx context.Context, email string, rule MailRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	rules, err := m.ListRules(ctx, email)
+	if err != nil {
+		return err
+	}
+	for _, existing := range rules {
+		if existing.Name == rule.Name {
+			rule.ID = existing.ID
+			if rule.Sequence == 0 {
+				rule.Sequence = existing.Sequence
+			}
+			return m.UpdateRule(ctx, email, rule)
+		}
+	}
+	_, err = m.CreateRule(ctx, email, rule)
+	return err
+}
+
+// RuleApplyResult 下发规则到单个用户的结果
+type RuleApplyResult struct {
+	Email string
+	Err   error
+}
+
+// EnsureRuleForDepartment 将规则下发到部门内的全部直属用户，单个用户失败不影响其它用户
+func (m *MailFolderService) EnsureRuleForDepartment(ctx context.Context, deptID string, rule MailRule) ([]RuleApplyResult, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	var results []RuleApplyResult
+	for u, err := range m.Department.AllUsers(ctx, deptID) {
+		if err != nil {
+			return results, err
+		}
+		results = append(results, RuleApplyResult{Email: u.Email, Err: m.EnsureRule(ctx, u.Email, rule)})
+	}
+	return results, nil
+}
@@ -0,0 +1,262 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MailboxSettingsService 邮箱设置服务
+type MailboxSettingsService struct{ *Client }
+
+// MailboxSettings 邮箱基础设置
+type MailboxSettings struct {
+	Language string `json:"language,omitempty"` // 显示语言，如 zh_CN、en_US
+	TimeZone string `json:"timeZone,omitempty"` // 时区，如 Asia/Shanghai
+}
+
+type AutoReplyAudience string
+
+// 自动回复对外部发件人的回复范围
+const (
+	AutoReplyAudienceNone         AutoReplyAudience = "none"         // 不回复外部发件人
+	AutoReplyAudienceContactsOnly AutoReplyAudience = "contactsOnly" // 只回复联系人
+	AutoReplyAudienceAll          AutoReplyAudience = "all"          // 回复所有外部发件人
+)
+
+// AutoReply 自动回复（外出）设置
+type AutoReply struct {
+	Enabled              bool              `json:"enabled"`                        // 是否开启
+	ScheduledStartTime   *time.Time        `json:"scheduledStartTime,omitempty"`   // 生效开始时间，为空表示立即生效
+	ScheduledEndTime     *time.Time        `json:"scheduledEndTime,omitempty"`     // 生效结束时间，为空表示一直生效
+	InternalReplyMessage string            `json:"internalReplyMessage"`           // 回复组织内发件人的内容
+	ExternalReplyMessage string            `json:"externalReplyMessage,omitempty"` // 回复组织外发件人的内容，为空时使用内部内容
+	ExternalAudience     AutoReplyAudience `json:"externalAudience,omitempty"`     // 回复外部发件人的范围
+}
+
+// Forwarding 自动转发设置
+type Forwarding struct {
+	Enabled   bool     `json:"enabled"`   // 是否开启
+	Addresses []string `json:"addresses"` // 转发到的地址
+	KeepCopy  bool     `json:"keepCopy"`  // 是否在本邮箱保留副本
+}
+
+// Signature 签名
+type Signature struct {
+	ID                string `json:"id,omitempty"`      // 签名ID
+	Name              string `json:"name"`              // 签名名称
+	Content           string `json:"content"`           // 签名内容，HTML
+	IsDefaultForNew   bool   `json:"isDefaultForNew"`   // 是否为新邮件的默认签名
+	IsDefaultForReply bool   `json:"isDefaultForReply"` // 是否为回复/转发的默认签名
+}
+
+// MailboxQuota 邮箱容量
+type MailboxQuota struct {
+	Total int64 `json:"total"` // 总容量，字节
+	Used  int64 `json:"used"`  // 已使用，字节
+}
+
+// UsedRatio 返回已使用的比例，总容量为 0 时返回 0
+func (q MailboxQuota) UsedRatio() float64 {
+	if q.Total <= 0 {
+		return 0
+	}
+	return float64(q.Used) / float64(q.Total)
+}
+
+func settingsPath(email, suffix string) (string, error) {
+	if email == "" {
+		return "", fmt.Errorf("email is required")
+	}
+	return fmt.Sprintf("/v2/users/%s/mailboxSettings%s", email, suffix), nil
+}
+
+// Get 获取显示语言和时区
+func (s *MailboxSettingsService) Get(ctx context.Context, email string) (*MailboxSettings, error) {
+	path, err := settingsPath(email, "")
+	if err != nil {
+		return nil, err
+	}
+	var dataObj MailboxSettings
+	if err := s.doJSON(ctx, MethodGet, path, nil, &dataObj); err != nil {
+		return nil, err
+	}
+	return &dataObj, nil
+}
+
+// Update 修改显示语言和时区，空字段不修改
+func (s *MailboxSettingsService) Update(ctx context.Context, email string, settings MailboxSettings) error {
+	path, err := settingsPath(email, "")
+	if err != nil {
+		return err
+	}
+	if settings.TimeZone != "" {
+		if _, err := loadLocation(settings.TimeZone); err != nil {
+			return err
+		}
+	}
+	return s.doJSON(ctx, MethodPatch, path, settings, nil)
+}
+
+// GetAutoReply 获取自动回复设置
+func (s *MailboxSettingsService) GetAutoReply(ctx context.Context, email string) (*AutoReply, error) {
+	path, err := settingsPath(email, "/autoReply")
+	if err != nil {
+		return nil, err
+	}
+	var dataObj AutoReply
+	if err := s.doJSON(ctx, MethodGet, path, nil, &dataObj); err != nil {
+		return nil, err
+	}
+	return &dataObj, nil
+}
+
+// SetAutoReply 设置自动回复，会整体覆盖原有设置
+func (s *MailboxSettingsService) SetAutoReply(ctx context.Context, email string, reply AutoReply) error {
+	path, err := settingsPath(email, "/autoReply")
+	if err != nil {
+		return err
+	}
+	if reply.Enabled && reply.InternalReplyMessage == "" {
+		return fmt.Errorf("internalReplyMessage is required when auto reply is enabled")
+	}
+	if reply.ScheduledStartTime != nil && reply.ScheduledEndTime != nil && !reply.ScheduledEndTime.After(*reply.ScheduledStartTime) {
+		return fmt.Errorf("scheduledEndTime must be after scheduledStartTime")
+	}
+	return s.doJSON(ctx, MethodPut, path, reply, nil)
+}
+
+// GetForwarding 获取自动转发设置
+func (s *MailboxSettingsService) GetForwarding(ctx context.Context, email string) (*Forwarding, error) {
+	path, err := settingsPath(email, "/forwarding")
+	if err != nil {
+		return nil, err
+	}
+	var dataObj Forwarding
+	if err := s.doJSON(ctx, MethodGet, path, nil, &dataObj); err != nil {
+		return nil, err
+	}
+	return &dataObj, nil
+}
+
+// SetForwarding 设置自动转发，会整体覆盖原有设置
+func (s *MailboxSettingsService) SetForwarding(ctx context.Context, email string, fwd Forwarding) error {
+	path, err := settingsPath(email, "/forwarding")
+	if err != nil {
+		return err
+	}
+	if fwd.Enabled && len(fwd.Addresses) == 0 {
+		return fmt.Errorf("addresses are required when forwarding is enabled")
+	}
+	if fwd.Addresses == nil {
+		fwd.Addresses = []string{}
+	}
+	return s.doJSON(ctx, MethodPut, path, fwd, nil)
+}
+
+// GetQuota 获取邮箱容量和使用量
+func (s *MailboxSettingsService) GetQuota(ctx context.Context, email string) (*MailboxQuota, error) {
+	path, err := settingsPath(email, "/quota")
+	if err != nil {
+		return nil, err
+	}
+	var dataObj MailboxQuota
+	if err := s.doJSON(ctx, MethodGet, path, nil, &dataObj); err != nil {
+		return nil, err
+	}
+	return &dataObj, nil
+}
+
+type listSignaturesRsp struct {
+	Signatures []Signature `json:"signatures"`
+}
+
+// ListSignatures 获取用户的全部签名
+func (s *MailboxSettingsService) ListSignatures(ctx context.Context, email string) ([]Signature, error) {
+	if email == "" {
+		return nil, fmt.Errorf("email is required")
+	}
+	var dataObj listSignaturesRsp
+	if err := s.doJSON(ctx, MethodGet, fmt.Sprintf("/v2/users/%s/signatures", email), nil, &dataObj); err != nil {
+		return nil, err
+	}
+	return dataObj.Signatures, nil
+}
+
+// CreateSignature 创建签名
+func (s *MailboxSettingsService) CreateSignature(ctx context.Context, email string, sig Signature) (*Signature, error) {
+	if email == "" || sig.Name == "" {
+		return nil, fmt.Errorf("email and name are required")
+	}
+	var dataObj Signature
+	if err := s.doJSON(ctx, MethodPost, fmt.Sprintf("/v2/users/%s/signatures", email), sig, &dataObj); err != nil {
+		return nil, err
+	}
+	return &dataObj, nil
+}
+
+// UpdateSignature 更新签名
+func (s *MailboxSettingsService) UpdateSignature(ctx context.Context, email string, sig Signature) error {
+	if email == "" || sig.ID == "" {
+		return fmt.Errorf("email and signature id are required")
+	}
+	return s.doJSON(ctx, MethodPatch, fmt.Sprintf("/v2/users/%s/signatures/%s", email, sig.ID), sig, nil)
+}
+
+// DeleteSignature 删除签名
+func (s *MailboxSettingsService) DeleteSignature(ctx context.Context, email, id string) error {
+	if email == "" || id == "" {
+		return fmt.Errorf("email and signature id are required")
+	}
+	return s.doJSON(ctx, MethodDelete, fmt.Sprintf("/v2/users/%s/signatures/%s", email, id), nil, nil)
+}
+
+// SetDefaultSignature 创建或替换名为 name 的签名，并设为新邮件和回复的默认签名
+func (s *MailboxSettingsService) SetDefaultSignature(ctx context.Context, email, name, content string) (*Signature, error) {
+	sigs, err := s.ListSignatures(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	sig := Signature{Name: name, Content: content, IsDefaultForNew: true, IsDefaultForReply: true}
+	for _, existing := range sigs {
+		if existing.Name == name {
+			sig.ID = existing.ID
+			if err := s.UpdateSignature(ctx, email, sig); err != nil {
+				return nil, err
+			}
+			return &sig, nil
+		}
+	}
+	return s.CreateSignature(ctx, email, sig)
+}
+
+// SetOutOfOffice 在 [start, end) 期间开启外出自动回复，内外部使用相同内容，常用于员工休假
+func (s *MailboxSettingsService) SetOutOfOffice(ctx context.Context, email, message string, start, end time.Time) error {
+	return s.SetAutoReply(ctx, email, AutoReply{
+		Enabled:              true,
+		ScheduledStartTime:   &start,
+		ScheduledEndTime:     &end,
+		InternalReplyMessage: message,
+		ExternalReplyMessage: message,
+		ExternalAudience:     AutoReplyAudienceAll,
+	})
+}
+
+// SetupDeparture 为离职员工开启长期自动回复，并将新邮件转发给交接人（保留副本）
+func (s *MailboxSettingsService) SetupDeparture(ctx context.Context, email, message string, forwardTo []string) error {
+	if err := s.SetAutoReply(ctx, email, AutoReply{
+		Enabled:              true,
+		InternalReplyMessage: message,
+		ExternalReplyMessage: message,
+		ExternalAudience:     AutoReplyAudienceAll,
+	}); err != nil {
+		return fmt.Errorf("set auto reply: %w", err)
+	}
+	if len(forwardTo) == 0 {
+		return nil
+	}
+	if err := s.SetForwarding(ctx, email, Forwarding{Enabled: true, Addresses: forwardTo, KeepCopy: true}); err != nil {
+		return fmt.Errorf("set forwarding: %w", err)
+	}
+	return nil
+}
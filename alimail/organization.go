@@ -42,6 +42,7 @@ func (d *OrganizationService) Get(ctx context.Context) (*Organization, error) {
 	return nil, parseAPIError(resp)
 }
 
+// UpdateOrganizationReq 更新组织信息的参数，会发送全部字段；只修改部分字段时请使用 Patch
 type UpdateOrganizationReq struct {
 	Name              string `json:"name"`
 	Introduction      string `json:"introduction"`
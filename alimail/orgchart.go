@@ -0,0 +1,226 @@
+package alimail
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 组织架构图导出
+
+// OrgChartFormat 组织架构图的导出格式
+type OrgChartFormat string
+
+const (
+	OrgChartCSV     OrgChartFormat = "csv"     // 每个用户一行：部门路径、姓名、邮箱、职位、上级邮箱
+	OrgChartJSON    OrgChartFormat = "json"    // 嵌套的部门树
+	OrgChartDOT     OrgChartFormat = "dot"     // Graphviz DOT
+	OrgChartMermaid OrgChartFormat = "mermaid" // Mermaid flowchart
+)
+
+// OrgChartOptions 导出组织架构图的选项
+type OrgChartOptions struct {
+	Format        OrgChartFormat // 导出格式，默认 CSV
+	IncludeHidden bool           // 是否包含隐藏的部门和用户，隐藏部门的下级部门同样会被排除
+	IncludeUsers  bool           // JSON、DOT、Mermaid 中是否包含用户，CSV 总是按用户输出
+}
+
+// ExportOrgChart 获取 rootID（为空时为$root）下的部门和用户，并按选项写出组织架构图
+func (d *DepartmentService) ExportOrgChart(ctx context.Context, rootID string, w io.Writer, opts OrgChartOptions) error {
+	var treeOpts []TreeOption
+	if opts.Format == "" || opts.Format == OrgChartCSV || opts.IncludeUsers {
+		treeOpts = append(treeOpts, WithTreeUsers())
+	}
+	tree, err := d.Tree(ctx, rootID, treeOpts...)
+	if err != nil {
+		return err
+	}
+	return tree.WriteOrgChart(w, opts)
+}
+
+// WriteOrgChart 将内存中的组织架构树按选项写出，CSV 与包含用户的导出需要树中已有用户（WithTreeUsers）
+func (t *OrgTree) WriteOrgChart(w io.Writer, opts OrgChartOptions) error {
+	switch opts.Format {
+	case "", OrgChartCSV:
+		return t.writeOrgChartCSV(w, opts)
+	case OrgChartJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(t.chartJSON(t.Root, opts))
+	case OrgChartDOT, OrgChartMermaid:
+		bw := bufio.NewWriter(w)
+		if opts.Format == OrgChartDOT {
+			t.writeOrgChartDOT(bw, opts)
+		} else {
+			t.writeOrgChartMermaid(bw, opts)
+		}
+		return bw.Flush()
+	}
+	return fmt.Errorf("unsupported org chart format %q", opts.Format)
+}
+
+// visibleWalk 先序遍历导出范围内的部门
+func (t *OrgTree) visibleWalk(opts OrgChartOptions, fn func(n *OrgNode)) {
+	t.Walk(func(n *OrgNode) bool {
+		if n != t.Root && n.IsHidden && !opts.IncludeHidden {
+			return false
+		}
+		fn(n)
+		return true
+	})
+}
+
+// visibleUsers 返回导出范围内的直属用户
+func visibleUsers(n *OrgNode, opts OrgChartOptions) []User {
+	var users []User
+	for _, u := range n.Users {
+		if !u.IsHidden || opts.IncludeHidden {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// userManagerEmail 返回用户的上级邮箱
+func userManagerEmail(u User) string {
+	if u.ManagerEmail != "" {
+		return u.ManagerEmail
+	}
+	return u.ManagerInfo.Email
+}
+
+func (t *OrgTree) writeOrgChartCSV(w io.Writer, opts OrgChartOptions) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"department", "name", "email", "jobTitle", "manager"}); err != nil {
+		return err
+	}
+	t.visibleWalk(opts, func(n *OrgNode) {
+		path := strings.Join(n.Path(), "/")
+		for _, u := range visibleUsers(n, opts) {
+			cw.Write([]string{path, u.Name, u.Email, u.JobTitle, userManagerEmail(u)})
+		}
+	})
+	cw.Flush()
+	return cw.Error()
+}
+
+type orgChartUser struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	JobTitle string `json:"jobTitle,omitempty"`
+	Manager  string `json:"manager,omitempty"`
+	IsHidden bool   `json:"isHidden,omitempty"`
+}
+
+type orgChartDepartment struct {
+	ID       string                `json:"id"`
+	Name     string                `json:"name"`
+	Email    string                `json:"email,omitempty"`
+	Managers []string              `json:"managers,omitempty"`
+	IsHidden bool                  `json:"isHidden,omitempty"`
+	Users    []orgChartUser        `json:"users,omitempty"`
+	Children []*orgChartDepartment `json:"children,omitempty"`
+}
+
+func (t *OrgTree) chartJSON(n *OrgNode, opts OrgChartOptions) *orgChartDepartment {
+	dept := &orgChartDepartment{
+		ID:       n.ID,
+		Name:     n.Name,
+		Email:    n.Email,
+		Managers: n.Department.Managers,
+		IsHidden: n.IsHidden,
+	}
+	if opts.IncludeUsers {
+		for _, u := range visibleUsers(n, opts) {
+			dept.Users = append(dept.Users, orgChartUser{
+				Name:     u.Name,
+				Email:    u.Email,
+				JobTitle: u.JobTitle,
+				Manager:  userManagerEmail(u),
+				IsHidden: u.IsHidden,
+			})
+		}
+	}
+	for _, c := range n.Children {
+		if c.IsHidden && !opts.IncludeHidden {
+			continue
+		}
+		dept.Children = append(dept.Children, t.chartJSON(c, opts))
+	}
+	return dept
+}
+
+// chartIDs 为节点分配只含字母数字的标识，部门ID中可能含有 $ 等 DOT/Mermaid 不接受的字符
+type chartIDs map[string]string
+
+func (ids chartIDs) get(prefix, key string) string {
+	if id, ok := ids[prefix+key]; ok {
+		return id
+	}
+	id := fmt.Sprintf("%s%d", prefix, len(ids))
+	ids[prefix+key] = id
+	return id
+}
+
+func (t *OrgTree) writeOrgChartDOT(w *bufio.Writer, opts OrgChartOptions) {
+	ids := chartIDs{}
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+	fmt.Fprintln(w, "digraph orgchart {")
+	fmt.Fprintln(w, "  rankdir=TB;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	t.visibleWalk(opts, func(n *OrgNode) {
+		id := ids.get("d", n.ID)
+		fmt.Fprintf(w, "  %s [label=%s];\n", id, quote(n.Name))
+		if n.Parent != nil {
+			fmt.Fprintf(w, "  %s -> %s;\n", ids.get("d", n.Parent.ID), id)
+		}
+		if !opts.IncludeUsers {
+			return
+		}
+		for _, u := range visibleUsers(n, opts) {
+			uid := ids.get("u", n.ID+"/"+u.ID)
+			label := u.Name
+			if u.JobTitle != "" {
+				label += "\n" + u.JobTitle
+			}
+			fmt.Fprintf(w, "  %s [label=%s, shape=ellipse];\n", uid, quote(label))
+			fmt.Fprintf(w, "  %s -> %s;\n", id, uid)
+		}
+	})
+	fmt.Fprintln(w, "}")
+}
+
+func (t *OrgTree) writeOrgChartMermaid(w *bufio.Writer, opts OrgChartOptions) {
+	ids := chartIDs{}
+	// Mermaid 的标签用 #quot; 表示双引号
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s) + `"`
+	}
+	fmt.Fprintln(w, "flowchart TD")
+	t.visibleWalk(opts, func(n *OrgNode) {
+		id := ids.get("d", n.ID)
+		fmt.Fprintf(w, "  %s[%s]\n", id, quote(n.Name))
+		if n.Parent != nil {
+			fmt.Fprintf(w, "  %s --> %s\n", ids.get("d", n.Parent.ID), id)
+		}
+		if !opts.IncludeUsers {
+			return
+		}
+		for _, u := range visibleUsers(n, opts) {
+			uid := ids.get("u", n.ID+"/"+u.ID)
+			label := u.Name
+			if u.JobTitle != "" {
+				label += "\n" + u.JobTitle
+			}
+			fmt.Fprintf(w, "  %s(%s)\n", uid, quote(label))
+			fmt.Fprintf(w, "  %s --> %s\n", id, uid)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package alimail
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// chartTree 内存中的小组织架构树：研发"核心"(A) 下有 平台(A1)，隐藏部门 秘密(H) 与隐藏用户不会导出
+func chartTree() *OrgTree {
+	root := &OrgNode{Department: Department{ID: RootDepartmentID, Name: "root"}}
+	a := &OrgNode{
+		Department: Department{ID: "A", Name: `研发"核心"`, Email: "dev@example.com", Managers: []string{"m1"}},
+		Parent:     root,
+		Depth:      1,
+	}
+	zhang := User{ID: "u1", Name: "张三", Email: "u1@example.com", JobTitle: "工程师"}
+	zhang.ManagerInfo.Email = "m1@example.com"
+	a.Users = []User{zhang, {ID: "u9", Name: "隐士", Email: "u9@example.com", IsHidden: true}}
+	a1 := &OrgNode{Department: Department{ID: "A1", Name: "平台"}, Parent: a, Depth: 2}
+	a1.Users = []User{{ID: "u2", Name: "李, 四", Email: "u2@example.com", JobTitle: `"高级"工程师`, ManagerEmail: "u1@example.com"}}
+	h := &OrgNode{Department: Department{ID: "H", Name: "秘密", IsHidden: true}, Parent: root, Depth: 1}
+	root.Children = []*OrgNode{a, h}
+	a.Children = []*OrgNode{a1}
+	tree := &OrgTree{Root: root, byID: map[string]*OrgNode{}}
+	for _, n := range []*OrgNode{root, a, a1, h} {
+		tree.byID[n.ID] = n
+	}
+	return tree
+}
+
+func TestWriteOrgChart(t *testing.T) {
+	cases := []struct {
+		format OrgChartFormat
+		want   []string
+	}{
+		{OrgChartCSV, []string{
+			"department,name,email,jobTitle,manager",
+			`"研发""核心""",张三,u1@example.com,工程师,m1@example.com`,
+			`"研发""核心""/平台","李, 四",u2@example.com,"""高级""工程师",u1@example.com`,
+			"",
+		}},
+		{OrgChartJSON, []string{
+			"{",
+			`  "id": "$root",`,
+			`  "name": "root",`,
+			`  "children": [`,
+			"    {",
+			`      "id": "A",`,
+			`      "name": "研发\"核心\"",`,
+			`      "email": "dev@example.com",`,
+			`      "managers": [`,
+			`        "m1"`,
+			"      ],",
+			`      "users": [`,
+			"        {",
+			`          "name": "张三",`,
+			`          "email": "u1@example.com",`,
+			`          "jobTitle": "工程师",`,
+			`          "manager": "m1@example.com"`,
+			"        }",
+			"      ],",
+			`      "children": [`,
+			"        {",
+			`          "id": "A1",`,
+			`          "name": "平台",`,
+			`          "users": [`,
+			"            {",
+			`              "name": "李, 四",`,
+			`              "email": "u2@example.com",`,
+			`              "jobTitle": "\"高级\"工程师",`,
+			`              "manager": "u1@example.com"`,
+			"            }",
+			"          ]",
+			"        }",
+			"      ]",
+			"    }",
+			"  ]",
+			"}",
+			"",
+		}},
+		{OrgChartDOT, []string{
+			"digraph orgchart {",
+			"  rankdir=TB;",
+			"  node [shape=box];",
+			`  d0 [label="root"];`,
+			`  d1 [label="研发\"核心\""];`,
+			"  d0 -> d1;",
+			`  u2 [label="张三\n工程师", shape=ellipse];`,
+			"  d1 -> u2;",
+			`  d3 [label="平台"];`,
+			"  d1 -> d3;",
+			`  u4 [label="李, 四\n\"高级\"工程师", shape=ellipse];`,
+			"  d3 -> u4;",
+			"}",
+			"",
+		}},
+		{OrgChartMermaid, []string{
+			"flowchart TD",
+			`  d0["root"]`,
+			`  d1["研发#quot;核心#quot;"]`,
+			"  d0 --> d1",
+			`  u2("张三<br/>工程师")`,
+			"  d1 --> u2",
+			`  d3["平台"]`,
+			"  d1 --> d3",
+			`  u4("李, 四<br/>#quot;高级#quot;工程师")`,
+			"  d3 --> u4",
+			"",
+		}},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := chartTree().WriteOrgChart(&buf, OrgChartOptions{Format: c.format, IncludeUsers: true}); err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.Join(c.want, "\n"); buf.String() != want {
+			t.Errorf("%s =\n%s\nwant\n%s", c.format, buf.String(), want)
+		}
+	}
+	if err := chartTree().WriteOrgChart(&bytes.Buffer{}, OrgChartOptions{Format: "svg"}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
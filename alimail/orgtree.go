@@ -0,0 +1,302 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// OrgNode 组织架构树中的部门节点
+type OrgNode struct {
+	Department
+	Parent   *OrgNode   // 父节点，根节点为 nil
+	Children []*OrgNode // 子部门
+	Users    []User     // 直属用户，仅在使用 WithTreeUsers 时填充
+	Depth    int        // 深度，根节点为 0
+}
+
+// Path 返回从树根（不含）到该节点的部门名称
+func (n *OrgNode) Path() []string {
+	var path []string
+	for cur := n; cur != nil && cur.Parent != nil; cur = cur.Parent {
+		path = append(path, cur.Name)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// OrgTree 内存中的组织架构树
+type OrgTree struct {
+	Root *OrgNode
+	byID map[string]*OrgNode
+}
+
+type treeConfig struct {
+	withUsers   bool
+	concurrency int
+}
+
+// TreeOption 获取组织架构树的选项
+type TreeOption func(*treeConfig)
+
+// WithTreeUsers 同时获取每个部门的直属用户
+func WithTreeUsers() TreeOption {
+	return func(c *treeConfig) { c.withUsers = true }
+}
+
+// WithTreeConcurrency 设置同时请求的部门数，默认 4
+func WithTreeConcurrency(n int) TreeOption {
+	return func(c *treeConfig) { c.concurrency = n }
+}
+
+// Tree 从 rootID（为空时为$root）开始递归获取部门树，以有限的并发请求子部门
+func (d *DepartmentService) Tree(ctx context.Context, rootID string, opts ...TreeOption) (*OrgTree, error) {
+	cfg := treeConfig{concurrency: 4}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.concurrency <= 0 {
+		cfg.concurrency = 1
+	}
+	if rootID == "" {
+		rootID = RootDepartmentID
+	}
+	rootDept, err := d.GetDepartment(ctx, rootID)
+	if err != nil {
+		return nil, fmt.Errorf("get root department %s: %w", rootID, err)
+	}
+	if rootDept.ID == "" {
+		rootDept.ID = rootID
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tree := &OrgTree{Root: &OrgNode{Department: *rootDept}, byID: map[string]*OrgNode{}}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, cfg.concurrency)
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	var visit func(node *OrgNode)
+	visit = func(node *OrgNode) {
+		defer wg.Done()
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		children, err := collectSeq(d.AllDepts(ctx, node.ID))
+		var users []User
+		if err == nil && cfg.withUsers {
+			users, err = collectSeq(d.AllUsers(ctx, node.ID))
+		}
+		<-sem
+		if err != nil {
+			fail(fmt.Errorf("department %s: %w", node.ID, err))
+			return
+		}
+
+		mu.Lock()
+		node.Users = users
+		for _, child := range children {
+			if _, dup := tree.byID[child.ID]; dup {
+				mu.Unlock()
+				fail(fmt.Errorf("department %s appears more than once in the tree", child.ID))
+				return
+			}
+			c := &OrgNode{Department: child, Parent: node, Depth: node.Depth + 1}
+			node.Children = append(node.Children, c)
+			tree.byID[c.ID] = c
+		}
+		mu.Unlock()
+
+		for _, c := range node.Children {
+			wg.Add(1)
+			go visit(c)
+		}
+	}
+
+	tree.byID[tree.Root.ID] = tree.Root
+	wg.Add(1)
+	go visit(tree.Root)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return tree, ctx.Err()
+}
+
+// Len 返回树中的部门数
+func (t *OrgTree) Len() int { return len(t.byID) }
+
+// Get 根据部门ID查找节点
+func (t *OrgTree) Get(id string) *OrgNode { return t.byID[id] }
+
+// FindByPath 根据从树根开始的名称路径查找节点，如 FindByPath("研发中心", "平台部")。
+// 同一父部门下存在同名子部门时会返回多个节点。
+func (t *OrgTree) FindByPath(segments ...string) []*OrgNode {
+	nodes := []*OrgNode{t.Root}
+	for _, seg := range segments {
+		var next []*OrgNode
+		for _, n := range nodes {
+			for _, c := range n.Children {
+				if c.Name == seg {
+					next = append(next, c)
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// Lookup 根据以 / 分隔的名称路径查找唯一的节点，如 "研发中心/平台部/SRE"
+func (t *OrgTree) Lookup(path string) (*OrgNode, error) {
+	nodes := t.FindByPath(SplitDepartmentPath(path)...)
+	switch len(nodes) {
+	case 0:
+		return nil, fmt.Errorf("department %q not found", path)
+	case 1:
+		return nodes[0], nil
+	}
+	return nil, fmt.Errorf("department path %q is ambiguous: %d departments match", path, len(nodes))
+}
+
+// SplitDepartmentPath 将以 / 分隔的部门路径拆分为名称，忽略空段和首尾空白
+func SplitDepartmentPath(path string) []string {
+	var segments []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg = strings.TrimSpace(seg); seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+// Ancestors 返回部门的所有上级部门，从树根开始，不含自身
+func (t *OrgTree) Ancestors(id string) []*OrgNode {
+	n := t.byID[id]
+	if n == nil {
+		return nil
+	}
+	var list []*OrgNode
+	for cur := n.Parent; cur != nil; cur = cur.Parent {
+		list = append(list, cur)
+	}
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+// Descendants 按先序返回部门的所有下级部门，不含自身
+func (t *OrgTree) Descendants(id string) []*OrgNode {
+	n := t.byID[id]
+	if n == nil {
+		return nil
+	}
+	var list []*OrgNode
+	t.walk(n, func(node *OrgNode) bool {
+		if node != n {
+			list = append(list, node)
+		}
+		return true
+	})
+	return list
+}
+
+// IsAncestor 判断 ancestorID 是否为 id 的上级部门（不含自身）
+func (t *OrgTree) IsAncestor(ancestorID, id string) bool {
+	n := t.byID[id]
+	if n == nil {
+		return false
+	}
+	for cur := n.Parent; cur != nil; cur = cur.Parent {
+		if cur.ID == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+// Depth 返回部门的深度，根节点为 0，不存在时返回 -1
+func (t *OrgTree) Depth(id string) int {
+	if n := t.byID[id]; n != nil {
+		return n.Depth
+	}
+	return -1
+}
+
+// Managers 返回部门的主管 id，部门本身未设置时沿上级部门向上查找
+func (t *OrgTree) Managers(id string) []string {
+	for cur := t.byID[id]; cur != nil; cur = cur.Parent {
+		if len(cur.Department.Managers) > 0 {
+			return cur.Department.Managers
+		}
+	}
+	return nil
+}
+
+// mergeDepartmentIds 将用户在部门 deptID 的成员列表中返回的部门ID并入 ids，
+// 成员列表只返回当前部门，同一用户出现在多个部门中时需要合并
+func mergeDepartmentIds(ids []string, u User, deptID string) []string {
+	for _, id := range append(slices.Clone(u.DepartmentIds), deptID) {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// UsersUnder 返回部门及其所有下级部门中的用户，同一用户只返回一次。
+// 需要在获取树时使用 WithTreeUsers。
+func (t *OrgTree) UsersUnder(id string) []User {
+	n := t.byID[id]
+	if n == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var users []User
+	t.walk(n, func(node *OrgNode) bool {
+		for _, u := range node.Users {
+			if !seen[u.ID] {
+				seen[u.ID] = true
+				users = append(users, u)
+			}
+		}
+		return true
+	})
+	return users
+}
+
+// Walk 先序遍历整棵树，fn 返回 false 时不再深入该节点的子部门
+func (t *OrgTree) Walk(fn func(node *OrgNode) bool) {
+	t.walk(t.Root, fn)
+}
+
+func (t *OrgTree) walk(n *OrgNode, fn func(*OrgNode) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.Children {
+		t.walk(c, fn)
+	}
+}
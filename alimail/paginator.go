@@ -0,0 +1,148 @@
+package alimail
+
+import (
+	"context"
+	"iter"
+)
+
+// MaxPageSize 分页接口单页最大数量
+const MaxPageSize = 100
+
+// PageFetcher 按偏移和页大小获取一页数据，同时返回服务端报告的总数
+type PageFetcher[T any] func(ctx context.Context, offset, limit int) (items []T, total int, err error)
+
+type paginatorConfig struct {
+	pageSize int
+	offset   int
+	prefetch bool
+}
+
+// PaginatorOption 分页器选项
+type PaginatorOption func(*paginatorConfig)
+
+// WithPageSize 设置每页大小，超过 MaxPageSize 时按 MaxPageSize 处理
+func WithPageSize(n int) PaginatorOption {
+	return func(c *paginatorConfig) { c.pageSize = n }
+}
+
+// WithStartOffset 设置起始偏移
+func WithStartOffset(n int) PaginatorOption {
+	return func(c *paginatorConfig) { c.offset = n }
+}
+
+// WithPrefetch 在调用方处理当前页时提前获取下一页
+func WithPrefetch(enabled bool) PaginatorOption {
+	return func(c *paginatorConfig) { c.prefetch = enabled }
+}
+
+// Paginator 通用的 offset/limit 分页器，所有分页接口都通过它遍历
+type Paginator[T any] struct {
+	fetch PageFetcher[T]
+	cfg   paginatorConfig
+}
+
+// NewPaginator 创建分页器，默认每页 MaxPageSize 条且不预取
+func NewPaginator[T any](fetch PageFetcher[T], opts ...PaginatorOption) *Paginator[T] {
+	cfg := paginatorConfig{pageSize: MaxPageSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.pageSize <= 0 || cfg.pageSize > MaxPageSize {
+		cfg.pageSize = MaxPageSize
+	}
+	if cfg.offset < 0 {
+		cfg.offset = 0
+	}
+	return &Paginator[T]{fetch: fetch, cfg: cfg}
+}
+
+type fetchedPage[T any] struct {
+	items []T
+	total int
+	err   error
+}
+
+// Pages 按页遍历，出错时产出一次错误后结束；调用方提前 break 时会取消尚未完成的预取
+func (p *Paginator[T]) Pages(ctx context.Context) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		fetch := func(offset int) fetchedPage[T] {
+			items, total, err := p.fetch(ctx, offset, p.cfg.pageSize)
+			return fetchedPage[T]{items: items, total: total, err: err}
+		}
+		offset := p.cfg.offset
+		cur := fetch(offset)
+		for {
+			if cur.err != nil {
+				yield(nil, cur.err)
+				return
+			}
+			next := offset + len(cur.items)
+			// 服务端未返回总数时，以不满一页作为结束条件
+			more := len(cur.items) == p.cfg.pageSize && (cur.total == 0 || next < cur.total)
+
+			var pending chan fetchedPage[T]
+			if more && p.cfg.prefetch {
+				// 带缓冲，提前结束时 goroutine 也不会阻塞
+				pending = make(chan fetchedPage[T], 1)
+				go func() { pending <- fetch(next) }()
+			}
+			if len(cur.items) > 0 && !yield(cur.items, nil) {
+				return
+			}
+			if !more {
+				return
+			}
+			if pending != nil {
+				cur = <-pending
+			} else {
+				cur = fetch(next)
+			}
+			offset = next
+		}
+	}
+}
+
+// All 逐条遍历全部数据
+//
+//	for u, err := range client.Department.AllUsers(ctx, "$root") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(u.Email)
+//	}
+func (p *Paginator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range p.Pages(ctx) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Collect 获取全部数据
+func (p *Paginator[T]) Collect(ctx context.Context) ([]T, error) {
+	return collectSeq(p.All(ctx))
+}
+
+// collectSeq 将迭代器中的数据收集为切片，遇到错误立即返回
+func collectSeq[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var all []T
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, item)
+	}
+	return all, nil
+}
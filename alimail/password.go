@@ -0,0 +1,193 @@
+package alimail
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 默认的特殊字符集合
+const defaultPasswordSymbols = "!@#$%^&*()-_=+[]{}<>?"
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	MinLength     int      // 最小长度
+	MaxLength     int      // 最大长度，0 表示不限制
+	RequireUpper  bool     // 必须包含大写字母
+	RequireLower  bool     // 必须包含小写字母
+	RequireDigit  bool     // 必须包含数字
+	RequireSymbol bool     // 必须包含特殊字符
+	MinClasses    int      // 至少包含几类字符（大写、小写、数字、特殊字符）
+	BannedWords   []string // 不允许包含的词（不区分大小写）
+	Symbols       string   // 生成密码时使用的特殊字符，默认 !@#$%^&*()-_=+[]{}<>?
+}
+
+// DefaultPasswordPolicy 默认密码策略：8 到 64 位，至少包含三类字符
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:  8,
+	MaxLength:  64,
+	MinClasses: 3,
+}
+
+// PasswordContext 校验密码时的上下文，用于禁止密码包含姓名、邮箱前缀或与旧密码相同
+type PasswordContext struct {
+	Name        string // 用户姓名
+	Email       string // 用户邮箱
+	OldPassword string // 旧密码
+}
+
+// PasswordPolicyError 密码不满足策略时返回的错误，包含全部不满足的原因
+type PasswordPolicyError struct {
+	Reasons []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet policy: " + strings.Join(e.Reasons, "; ")
+}
+
+// Check 校验密码是否满足策略
+func (p PasswordPolicy) Check(password string, pc PasswordContext) error {
+	var reasons []string
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		reasons = append(reasons, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol, space bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsSpace(r):
+			space = true
+		default:
+			symbol = true
+		}
+	}
+	if space {
+		reasons = append(reasons, "must not contain whitespace")
+	}
+	if p.RequireUpper && !upper {
+		reasons = append(reasons, "must contain an upper case letter")
+	}
+	if p.RequireLower && !lower {
+		reasons = append(reasons, "must contain a lower case letter")
+	}
+	if p.RequireDigit && !digit {
+		reasons = append(reasons, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		reasons = append(reasons, "must contain a special character")
+	}
+	classes := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		reasons = append(reasons, fmt.Sprintf("must contain at least %d of upper case, lower case, digit and special character", p.MinClasses))
+	}
+
+	lowerPwd := strings.ToLower(password)
+	banned := append([]string{}, p.BannedWords...)
+	if pc.Name != "" {
+		banned = append(banned, pc.Name)
+	}
+	if local, _, _ := strings.Cut(pc.Email, "@"); local != "" {
+		banned = append(banned, local)
+	}
+	for _, w := range banned {
+		// 过短的词容易误伤，不参与检查
+		if utf8.RuneCountInString(w) < 3 {
+			continue
+		}
+		if strings.Contains(lowerPwd, strings.ToLower(w)) {
+			reasons = append(reasons, fmt.Sprintf("must not contain %q", w))
+		}
+	}
+	if pc.OldPassword != "" && password == pc.OldPassword {
+		reasons = append(reasons, "must not reuse the old password")
+	}
+
+	if len(reasons) > 0 {
+		return &PasswordPolicyError{Reasons: reasons}
+	}
+	return nil
+}
+
+// Generate 使用 crypto/rand 生成满足策略的密码，长度为 max(MinLength, 16)，且不超过 MaxLength
+func (p PasswordPolicy) Generate(pc PasswordContext) (string, error) {
+	length := max(p.MinLength, 16)
+	if p.MaxLength > 0 && length > p.MaxLength {
+		length = p.MaxLength
+	}
+	symbols := p.Symbols
+	if symbols == "" {
+		symbols = defaultPasswordSymbols
+	}
+	// 去掉容易混淆的字符
+	classes := []string{"ABCDEFGHJKLMNPQRSTUVWXYZ", "abcdefghijkmnpqrstuvwxyz", "23456789", symbols}
+	if length < len(classes) {
+		return "", fmt.Errorf("password length %d is too short to contain all character classes", length)
+	}
+	all := strings.Join(classes, "")
+
+	for attempt := 0; attempt < 100; attempt++ {
+		buf := make([]byte, 0, length)
+		// 每类字符至少一个，保证满足 Require* 和 MinClasses
+		for _, class := range classes {
+			c, err := randomChar(class)
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, c)
+		}
+		for len(buf) < length {
+			c, err := randomChar(all)
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, c)
+		}
+		if err := shuffle(buf); err != nil {
+			return "", err
+		}
+		pwd := string(buf)
+		if p.Check(pwd, pc) == nil {
+			return pwd, nil
+		}
+	}
+	return "", errors.New("failed to generate a password that meets the policy")
+}
+
+func randomChar(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[n.Int64()], nil
+}
+
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+		j := n.Int64()
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
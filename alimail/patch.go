@@ -0,0 +1,153 @@
+package alimail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// 部分更新：字段为 nil 表示不修改，非 nil 时即使是零值也会发送，
+// 因此可以显式地把 IsHidden 改为 false、清空部门列表等。
+
+// Ptr 返回 v 的指针，便于构造部分更新的参数
+//
+//	client.User.Patch(ctx, alimail.PatchUserReq{
+//		BaseUserReq: alimail.BaseUserReq{Email: "a@example.com"},
+//		Phone:       alimail.Ptr("13800000000"),
+//	})
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// PatchUserReq 部分更新用户的参数
+type PatchUserReq struct {
+	BaseUserReq                   `json:"-"`          // 用户ID或邮箱
+	Name                          *string             `json:"name,omitempty"`                          // 用户姓名
+	Nickname                      *string             `json:"nickname,omitempty"`                      // 用户昵称
+	EmployeeNo                    *string             `json:"employeeNo,omitempty"`                    // 员工编号
+	JobTitle                      *string             `json:"jobTitle,omitempty"`                      // 职位
+	WorkLocation                  *string             `json:"workLocation,omitempty"`                  // 工作地点
+	OfficeLocation                *string             `json:"officeLocation,omitempty"`                // 办公地点
+	HomeLocation                  *string             `json:"homeLocation,omitempty"`                  // 家庭住址
+	DepartmentIds                 *[]string           `json:"departmentIds,omitempty"`                 // 部门ID列表
+	Phone                         *string             `json:"phone,omitempty"`                         // 手机号
+	WorkPhone                     *string             `json:"workPhone,omitempty"`                     // 工作电话
+	Status                        *EmailAccountStatus `json:"status,omitempty"`                        // 用户状态
+	CustomName                    *string             `json:"customName,omitempty"`                    // 自定义名称
+	ManagerEmail                  *string             `json:"managerEmail,omitempty"`                  // 上级邮箱
+	IsHidden                      *bool               `json:"isHidden,omitempty"`                      // 是否隐藏
+	Info                          *string             `json:"info,omitempty"`                          // 信息
+	ForceChangePasswordNextSignIn *bool               `json:"forceChangePasswordNextSignIn,omitempty"` // 下次登录是否强制修改密码
+}
+
+// Patch 部分更新用户信息，只发送非 nil 的字段
+func (u *UserService) Patch(ctx context.Context, req PatchUserReq) (*User, error) {
+	if req.Email == "" && req.ID == "" {
+		return nil, fmt.Errorf("id and email can't be empty at the same time")
+	}
+	if req.Name != nil && *req.Name == "" {
+		return nil, fmt.Errorf("name can't be set to empty")
+	}
+	var path string
+	if req.Email != "" {
+		path = fmt.Sprintf("/v2/users/%s", req.Email)
+	} else {
+		path = fmt.Sprintf("/v2/users/%s", req.ID)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if string(body) == "{}" {
+		return nil, fmt.Errorf("nothing to update")
+	}
+
+	resp, err := u.doRequest(ctx, MethodPatch, path, BaseHeader, body)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj User
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// PatchDepartmentReq 部分更新部门的参数
+type PatchDepartmentReq struct {
+	ID                       string    `json:"-"`                                  // 部门ID
+	Name                     *string   `json:"name,omitempty"`                     // 部门名称
+	ParentID                 *string   `json:"parentId,omitempty"`                 // 父部门 id
+	HiddenExcludeUsers       *[]string `json:"hiddenExcludeUsers,omitempty"`       // 部门隐藏后，哪些白名单帐号 id 可访问该部门
+	HiddenExcludeDepartments *[]string `json:"hiddenExcludeDepartments,omitempty"` // 部门隐藏后，哪些部门 id 可访问该部门
+	IsHidden                 *bool     `json:"isHidden,omitempty"`                 // 是否隐藏
+	Managers                 *[]string `json:"managers,omitempty"`                 // 部门主管的 id 列表
+	Email                    *string   `json:"email,omitempty"`                    // 部门邮件组地址
+}
+
+// Patch 部分更新部门信息，只发送非 nil 的字段
+func (d *DepartmentService) Patch(ctx context.Context, req PatchDepartmentReq) error {
+	if req.ID == "" {
+		return fmt.Errorf("id can't be empty")
+	}
+	if req.Name != nil && *req.Name == "" {
+		return fmt.Errorf("name can't be set to empty")
+	}
+	if req.ParentID != nil && *req.ParentID == "" {
+		return fmt.Errorf("parentId can't be set to empty")
+	}
+	path := "/v2/departments/" + req.ID
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if string(body) == "{}" {
+		return fmt.Errorf("nothing to update")
+	}
+
+	resp, err := d.doRequest(ctx, MethodPatch, path, BaseHeader, body)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
+
+// PatchOrganizationReq 部分更新组织信息的参数
+type PatchOrganizationReq struct {
+	Name              *string `json:"name,omitempty"`              // 组织名称
+	Introduction      *string `json:"introduction,omitempty"`      // 组织简介
+	Telephone         *string `json:"telephone,omitempty"`         // 组织电话
+	Address           *string `json:"address,omitempty"`           // 组织地址
+	PreferredLanguage *string `json:"preferredLanguage,omitempty"` // 首选语言
+}
+
+// Patch 部分更新组织信息，只发送非 nil 的字段
+func (d *OrganizationService) Patch(ctx context.Context, req PatchOrganizationReq) error {
+	path := "/v2/organization/$current"
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if string(body) == "{}" {
+		return fmt.Errorf("nothing to update")
+	}
+
+	resp, err := d.doRequest(ctx, MethodPatch, path, BaseHeader, body)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
@@ -0,0 +1,218 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// 部门调整：移动部门以及合并部门
+
+// MoveDepartment 将部门移动到新的父部门下，会先获取组织架构树，在本地检查是否会形成环
+func (d *DepartmentService) MoveDepartment(ctx context.Context, id, parentID string) error {
+	if id == "" || parentID == "" {
+		return fmt.Errorf("id and parentId are required")
+	}
+	tree, err := d.Tree(ctx, "")
+	if err != nil {
+		return fmt.Errorf("get department tree: %w", err)
+	}
+	if err := checkMove(tree, id, parentID); err != nil {
+		return err
+	}
+	return d.Patch(ctx, PatchDepartmentReq{ID: id, ParentID: Ptr(parentID)})
+}
+
+// checkMove 根据组织架构树检查 id 能否移动到 parentID 下
+func checkMove(tree *OrgTree, id, parentID string) error {
+	if id == RootDepartmentID {
+		return fmt.Errorf("can't move the root department")
+	}
+	if id == parentID {
+		return fmt.Errorf("can't move department %s into itself", id)
+	}
+	if tree.Get(id) == nil {
+		return fmt.Errorf("department %s not found", id)
+	}
+	if tree.Get(parentID) == nil {
+		return fmt.Errorf("department %s not found", parentID)
+	}
+	if tree.IsAncestor(id, parentID) {
+		return fmt.Errorf("can't move department %s under its descendant %s", id, parentID)
+	}
+	return nil
+}
+
+// MergeStepKind 合并部门时的操作类型
+type MergeStepKind string
+
+const (
+	MergeMoveDepartment MergeStepKind = "move-department" // 将子部门移动到目标部门下
+	MergeMoveUser       MergeStepKind = "move-user"       // 将用户从源部门改到目标部门
+	MergeCopyManagers   MergeStepKind = "copy-managers"   // 将源部门主管追加到目标部门
+	MergeDeleteSource   MergeStepKind = "delete-source"   // 删除源部门
+)
+
+// MergeStep 合并计划中的一步
+type MergeStep struct {
+	Kind   MergeStepKind
+	Target string // 操作对象，部门ID或用户邮箱
+	Detail string // 便于阅读的说明
+	Done   bool   // 是否已执行成功
+	Err    error  // 执行失败的原因
+
+	apply func(ctx context.Context) error
+}
+
+// MergePlan 合并部门的执行计划，按 Steps 的顺序执行
+type MergePlan struct {
+	SrcID  string
+	DstID  string
+	DryRun bool
+	Steps  []MergeStep
+}
+
+// String 输出可读的计划，已执行的步骤标记为 [x]，失败的步骤附带原因
+func (p *MergePlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "merge department %s into %s (%d steps)\n", p.SrcID, p.DstID, len(p.Steps))
+	for i, s := range p.Steps {
+		mark := " "
+		if s.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(&b, "%3d. [%s] %-15s %s", i+1, mark, s.Kind, s.Detail)
+		if s.Err != nil {
+			fmt.Fprintf(&b, " (failed: %v)", s.Err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// MergeDepartmentsReq 合并部门的参数
+type MergeDepartmentsReq struct {
+	SrcID  string // 源部门ID，合并后会被删除
+	DstID  string // 目标部门ID
+	DryRun bool   // 只生成计划，不做任何修改
+}
+
+// MergeDepartments 将源部门合并到目标部门：子部门移动到目标部门下，直属用户的部门改为目标部门，
+// 源部门主管追加到目标部门，最后删除源部门。
+// 按顺序执行，遇到错误立即停止且不会删除源部门，返回的计划中记录了每一步的执行情况。
+func (d *DepartmentService) MergeDepartments(ctx context.Context, req MergeDepartmentsReq) (*MergePlan, error) {
+	if req.SrcID == "" || req.DstID == "" {
+		return nil, fmt.Errorf("srcId and dstId are required")
+	}
+	if req.SrcID == RootDepartmentID {
+		return nil, fmt.Errorf("can't merge the root department")
+	}
+	tree, err := d.Tree(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("get department tree: %w", err)
+	}
+	// 目标部门不能是源部门本身或其子孙
+	if err := checkMove(tree, req.SrcID, req.DstID); err != nil {
+		return nil, err
+	}
+	src, err := d.GetDepartment(ctx, req.SrcID)
+	if err != nil {
+		return nil, fmt.Errorf("get source department: %w", err)
+	}
+	dst, err := d.GetDepartment(ctx, req.DstID)
+	if err != nil {
+		return nil, fmt.Errorf("get target department: %w", err)
+	}
+	plan := &MergePlan{SrcID: req.SrcID, DstID: req.DstID, DryRun: req.DryRun}
+
+	for _, child := range tree.Get(src.ID).Children {
+		plan.Steps = append(plan.Steps, MergeStep{
+			Kind:   MergeMoveDepartment,
+			Target: child.ID,
+			Detail: fmt.Sprintf("move %q (%s) under %q", child.Name, child.ID, dst.Name),
+			apply: func(ctx context.Context) error {
+				return d.Patch(ctx, PatchDepartmentReq{ID: child.ID, ParentID: Ptr(dst.ID)})
+			},
+		})
+	}
+
+	users, err := collectSeq(d.AllUsers(ctx, src.ID))
+	if err != nil {
+		return nil, fmt.Errorf("list users of %s: %w", src.ID, err)
+	}
+	for _, u := range users {
+		// 列表接口返回的用户信息不一定完整，重新获取以免覆盖用户在其它部门的归属
+		full, err := d.User.Get(ctx, BaseUserReq{ID: u.ID})
+		if err != nil {
+			return nil, fmt.Errorf("get user %s: %w", u.Email, err)
+		}
+		u := *full
+		ids := replaceDepartment(u.DepartmentIds, src.ID, dst.ID)
+		plan.Steps = append(plan.Steps, MergeStep{
+			Kind:   MergeMoveUser,
+			Target: u.Email,
+			Detail: fmt.Sprintf("%s departments %v -> %v", u.Email, u.DepartmentIds, ids),
+			apply: func(ctx context.Context) error {
+				_, err := d.User.Patch(ctx, PatchUserReq{BaseUserReq: BaseUserReq{ID: u.ID}, DepartmentIds: &ids})
+				return err
+			},
+		})
+	}
+
+	managers := slices.Clone(dst.Managers)
+	for _, m := range src.Managers {
+		if !slices.Contains(managers, m) {
+			managers = append(managers, m)
+		}
+	}
+	if len(managers) > len(dst.Managers) {
+		plan.Steps = append(plan.Steps, MergeStep{
+			Kind:   MergeCopyManagers,
+			Target: dst.ID,
+			Detail: fmt.Sprintf("managers of %q %v -> %v", dst.Name, dst.Managers, managers),
+			apply: func(ctx context.Context) error {
+				return d.Patch(ctx, PatchDepartmentReq{ID: dst.ID, Managers: &managers})
+			},
+		})
+	}
+
+	plan.Steps = append(plan.Steps, MergeStep{
+		Kind:   MergeDeleteSource,
+		Target: src.ID,
+		Detail: fmt.Sprintf("delete %q (%s)", src.Name, src.ID),
+		apply: func(ctx context.Context) error {
+			return d.Delete(ctx, src.ID)
+		},
+	})
+
+	if req.DryRun {
+		return plan, nil
+	}
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if err := step.apply(ctx); err != nil {
+			step.Err = err
+			return plan, fmt.Errorf("step %d %s %s: %w", i+1, step.Kind, step.Target, err)
+		}
+		step.Done = true
+	}
+	return plan, nil
+}
+
+// replaceDepartment 将部门列表中的 from 替换为 to，并去掉重复
+func replaceDepartment(ids []string, from, to string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == from {
+			id = to
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	if !slices.Contains(out, to) {
+		out = append(out, to)
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package alimail
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// reorgDirectory 假服务中的部门树：$root 下有 A、B，A 下有 A1，A 的直属用户 u1 同时属于部门 X
+func reorgDirectory(req recordedRequest) (int, any) {
+	if req.Method == MethodPatch && strings.HasPrefix(req.Path, "/v2/users/") {
+		return http.StatusOK, User{ID: "u1", Email: "u1@example.com"}
+	}
+	if req.Method != MethodGet {
+		return 0, nil
+	}
+	depts := map[string]Department{
+		RootDepartmentID: {ID: RootDepartmentID, Name: "root"},
+		"A":              {ID: "A", Name: "研发", ParentID: RootDepartmentID, Managers: []string{"m1"}},
+		"B":              {ID: "B", Name: "产品", ParentID: RootDepartmentID, Managers: []string{"m2"}},
+		"A1":             {ID: "A1", Name: "平台", ParentID: "A"},
+	}
+	first := req.Query.Get("offset") == "0"
+	parts := strings.Split(strings.TrimPrefix(req.Path, "/v2/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "departments":
+		return http.StatusOK, depts[parts[1]]
+	case len(parts) == 3 && parts[2] == "departments":
+		rsp := ListDepartmentDeptsRsp{Departments: []Department{}}
+		for _, d := range depts {
+			if d.ParentID == parts[1] {
+				rsp.Total++
+				if first {
+					rsp.Departments = append(rsp.Departments, d)
+				}
+			}
+		}
+		return http.StatusOK, rsp
+	case len(parts) == 3 && parts[2] == "users":
+		rsp := ListDepartmentUsersRsp{Users: []User{}}
+		if parts[1] == "A" {
+			rsp.Total = 1
+			if first {
+				// 列表接口只返回当前部门
+				rsp.Users = append(rsp.Users, User{ID: "u1", Email: "u1@example.com", DepartmentIds: []string{"A"}})
+			}
+		}
+		return http.StatusOK, rsp
+	case len(parts) == 2 && parts[0] == "users" && parts[1] == "u1":
+		return http.StatusOK, User{ID: "u1", Email: "u1@example.com", DepartmentIds: []string{"A", "X"}}
+	}
+	return 0, nil
+}
+
+// writes 返回修改类请求
+func writes(reqs []recordedRequest) []recordedRequest {
+	var out []recordedRequest
+	for _, r := range reqs {
+		if r.Method != MethodGet {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func TestMoveDepartment(t *testing.T) {
+	c, requests := newFakeServer(t, reorgDirectory)
+	ctx := context.Background()
+	if err := c.Department.MoveDepartment(ctx, "A", "A1"); err == nil || !strings.Contains(err.Error(), "descendant") {
+		t.Errorf("move under descendant: err = %v", err)
+	}
+	if err := c.Department.MoveDepartment(ctx, "A", "missing"); err == nil {
+		t.Error("move under missing department: expected error")
+	}
+	if w := writes(requests()); len(w) != 0 {
+		t.Fatalf("rejected moves sent %d writes", len(w))
+	}
+
+	if err := c.Department.MoveDepartment(ctx, "A1", "B"); err != nil {
+		t.Fatal(err)
+	}
+	w := writes(requests())
+	if len(w) != 1 || w[0].Method != MethodPatch || w[0].Path != "/v2/departments/A1" || !reflect.DeepEqual(w[0].Body, map[string]any{"parentId": "B"}) {
+		t.Errorf("writes = %+v", w)
+	}
+}
+
+func TestMergeDepartmentsPlan(t *testing.T) {
+	c, requests := newFakeServer(t, reorgDirectory)
+	ctx := context.Background()
+	if _, err := c.Department.MergeDepartments(ctx, MergeDepartmentsReq{SrcID: "A", DstID: "A1"}); err == nil {
+		t.Error("merge into descendant: expected error")
+	}
+
+	plan, err := c.Department.MergeDepartments(ctx, MergeDepartmentsReq{SrcID: "A", DstID: "B", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, s := range plan.Steps {
+		kinds = append(kinds, string(s.Kind)+" "+s.Target)
+	}
+	want := []string{
+		"move-department A1",
+		"move-user u1@example.com",
+		"copy-managers B",
+		"delete-source A",
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("steps = %q, want %q", kinds, want)
+	}
+	if w := writes(requests()); len(w) != 0 {
+		t.Fatalf("dry run sent %d writes", len(w))
+	}
+
+	if _, err := c.Department.MergeDepartments(ctx, MergeDepartmentsReq{SrcID: "A", DstID: "B"}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range writes(requests()) {
+		got = append(got, r.Method+" "+r.Path)
+	}
+	wantWrites := []string{
+		"PATCH /v2/departments/A1",
+		"PATCH /v2/users/u1",
+		"PATCH /v2/departments/B",
+		"DELETE /v2/departments/A",
+	}
+	if !reflect.DeepEqual(got, wantWrites) {
+		t.Fatalf("writes = %q, want %q", got, wantWrites)
+	}
+	// 重新获取的用户保留了部门 X
+	user := writes(requests())[1]
+	if ids := user.Body["departmentIds"]; !reflect.DeepEqual(ids, []any{"B", "X"}) {
+		t.Errorf("user departmentIds = %v", ids)
+	}
+	managers := writes(requests())[2]
+	if m := managers.Body["managers"]; !reflect.DeepEqual(m, []any{"m2", "m1"}) {
+		t.Errorf("managers = %v", m)
+	}
+}
@@ -0,0 +1,85 @@
+package alimail
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+)
+
+// ResetPasswordsReq 批量重置密码的参数
+type ResetPasswordsReq struct {
+	Users                         []BaseUserReq // 需要重置的用户
+	ForceChangePasswordNextSignIn bool          // 下次登录是否强制修改密码
+	// OutputFile 不为空时，成功重置的 "用户,密码" 以 CSV 写入该文件（权限 0600，文件不能已存在），
+	// 此时返回结果中不包含密码
+	OutputFile string
+}
+
+// ResetPasswordResult 单个用户的重置结果
+type ResetPasswordResult struct {
+	BaseUserReq
+	Password string // 新密码，设置了 OutputFile 时为空
+	Err      error  // 失败原因
+}
+
+// ResetPasswords 按密码策略为每个用户生成随机密码并重置，单个失败不影响其它用户
+func (d *UserService) ResetPasswords(ctx context.Context, req ResetPasswordsReq) ([]ResetPasswordResult, error) {
+	var (
+		file *os.File
+		w    *csv.Writer
+	)
+	if req.OutputFile != "" {
+		// 先创建文件，避免密码已经重置却无处保存
+		f, err := os.OpenFile(req.OutputFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		file, w = f, csv.NewWriter(f)
+	}
+
+	results := make([]ResetPasswordResult, 0, len(req.Users))
+	for _, u := range req.Users {
+		res := ResetPasswordResult{BaseUserReq: u}
+		pwd, err := d.PasswordPolicy().Generate(PasswordContext{Email: u.Email})
+		if err != nil {
+			res.Err = err
+			results = append(results, res)
+			continue
+		}
+		res.Err = d.ResetPassword(ctx, ResetUserPasswordReq{
+			BaseUserReq:                   u,
+			Password:                      pwd,
+			ForceChangePasswordNextSignIn: req.ForceChangePasswordNextSignIn,
+		})
+		if res.Err == nil {
+			if w != nil {
+				account := u.Email
+				if account == "" {
+					account = u.ID
+				}
+				// 每条立即落盘，中途出错时已重置的密码也不会丢失
+				w.Write([]string{account, pwd})
+				w.Flush()
+				if err := w.Error(); err != nil {
+					// 密码已经重置但无法保存，只能通过返回值交给调用方
+					res.Password = pwd
+					return append(results, res), fmt.Errorf("failed to write output file: %w", err)
+				}
+			} else {
+				res.Password = pwd
+			}
+		}
+		results = append(results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if file != nil {
+		if err := file.Sync(); err != nil {
+			return results, fmt.Errorf("failed to sync output file: %w", err)
+		}
+	}
+	return results, ctx.Err()
+}
@@ -0,0 +1,102 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LoginSession 用户的登录会话
+type LoginSession struct {
+	ID             string    `json:"id"`             // 会话ID
+	ClientType     string    `json:"clientType"`     // 客户端类型，如 web、mobile、pc
+	Protocol       string    `json:"protocol"`       // 登录协议，如 web、IMAP、POP3、SMTP
+	IP             string    `json:"ip"`             // 登录IP
+	UserAgent      string    `json:"userAgent"`      // 客户端标识
+	LoginTime      time.Time `json:"loginTime"`      // 登录时间
+	LastActiveTime time.Time `json:"lastActiveTime"` // 最后活跃时间
+}
+
+// EnableClientPassword 开启客户端专用密码，开启后 IMAP/POP/SMTP 只能使用客户端专用密码登录
+func (d *UserService) EnableClientPassword(ctx context.Context, req BaseUserReq) error {
+	return d.setClientPassword(ctx, req, true)
+}
+
+// DisableClientPassword 关闭客户端专用密码
+func (d *UserService) DisableClientPassword(ctx context.Context, req BaseUserReq) error {
+	return d.setClientPassword(ctx, req, false)
+}
+
+func (d *UserService) setClientPassword(ctx context.Context, req BaseUserReq, enabled bool) error {
+	path, err := req.path("")
+	if err != nil {
+		return err
+	}
+	return d.doJSON(ctx, MethodPatch, path, map[string]bool{"enableClientPassword": enabled}, nil)
+}
+
+type listSessionsRsp struct {
+	Sessions []LoginSession `json:"sessions"`
+}
+
+// ListSessions 获取用户当前有效的登录会话
+func (d *UserService) ListSessions(ctx context.Context, req BaseUserReq) ([]LoginSession, error) {
+	path, err := req.path("/sessions")
+	if err != nil {
+		return nil, err
+	}
+	var dataObj listSessionsRsp
+	if err := d.doJSON(ctx, MethodGet, path, nil, &dataObj); err != nil {
+		return nil, err
+	}
+	return dataObj.Sessions, nil
+}
+
+// RevokeSession 注销用户的单个登录会话
+func (d *UserService) RevokeSession(ctx context.Context, req BaseUserReq, sessionID string) error {
+	if sessionID == "" {
+		return fmt.Errorf("session id is required")
+	}
+	path, err := req.path("/sessions/" + sessionID)
+	if err != nil {
+		return err
+	}
+	return d.doJSON(ctx, MethodDelete, path, nil, nil)
+}
+
+// SignOut 强制用户在所有客户端上登出
+func (d *UserService) SignOut(ctx context.Context, req BaseUserReq) error {
+	path, err := req.path("/signOut")
+	if err != nil {
+		return err
+	}
+	return d.doJSON(ctx, MethodPost, path, nil, nil)
+}
+
+// RevokeCredentialsReq 凭据泄露后处置的参数
+type RevokeCredentialsReq struct {
+	BaseUserReq
+	NewPassword string // 新密码，下次登录时强制修改
+}
+
+// RevokeCredentials 凭据泄露后的处置：重置密码并要求下次登录修改、关闭客户端专用密码、强制登出所有会话。
+// 任一步骤失败都会立即返回，已完成的步骤不会回滚。
+func (d *UserService) RevokeCredentials(ctx context.Context, req RevokeCredentialsReq) error {
+	if req.NewPassword == "" {
+		return fmt.Errorf("new password is required")
+	}
+	if err := d.ResetPassword(ctx, ResetUserPasswordReq{
+		BaseUserReq:                   req.BaseUserReq,
+		Password:                      req.NewPassword,
+		ForceChangePasswordNextSignIn: true,
+	}); err != nil {
+		return fmt.Errorf("reset password: %w", err)
+	}
+	if err := d.DisableClientPassword(ctx, req.BaseUserReq); err != nil {
+		return fmt.Errorf("disable client password: %w", err)
+	}
+	if err := d.SignOut(ctx, req.BaseUserReq); err != nil {
+		return fmt.Errorf("sign out: %w", err)
+	}
+	return nil
+}
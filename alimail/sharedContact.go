@@ -1,6 +1,12 @@
 package alimail
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -26,3 +32,251 @@ type SharedContact struct {
 	Info         string    `json:"info"`
 	IsHidden     bool      `json:"isHidden"`
 }
+
+// SharedContactReq 创建公共联系人的参数
+type SharedContactReq struct {
+	Name        string `json:"name"`                  // 姓名
+	FolderID    string `json:"folderId"`              // 所属分组ID
+	Email       string `json:"email,omitempty"`       // 邮箱
+	WorkPhone   string `json:"workPhone,omitempty"`   // 工作电话
+	Phone       string `json:"phone,omitempty"`       // 手机号
+	HomeAddress string `json:"homeAddress,omitempty"` // 家庭住址
+	CompanyName string `json:"companyName,omitempty"` // 公司
+	JobTitle    string `json:"jobTitle,omitempty"`    // 职位
+	WorkAddress string `json:"workAddress,omitempty"` // 工作地址
+	Nickname    string `json:"nickname,omitempty"`    // 昵称
+	Manager     string `json:"manager,omitempty"`     // 上级
+	Info        string `json:"info,omitempty"`        // 备注
+	IsHidden    bool   `json:"isHidden,omitempty"`    // 是否隐藏
+}
+
+// Create 创建公共联系人
+func (s *SharedContactService) Create(ctx context.Context, req SharedContactReq) (*SharedContact, error) {
+	if req.Name == "" || req.FolderID == "" {
+		return nil, fmt.Errorf("name and folderId are required")
+	}
+	path := "/v2/sharedContacts"
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := s.doRequest(ctx, MethodPost, path, BaseHeader, body)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj SharedContact
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// Get 获取公共联系人
+func (s *SharedContactService) Get(ctx context.Context, id string) (*SharedContact, error) {
+	if id == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	path := fmt.Sprintf("/v2/sharedContacts/%s", id)
+
+	resp, err := s.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj SharedContact
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// UpdateSharedContactReq 更新公共联系人的参数，字段为 nil 表示不修改，
+// 非 nil 时即使是零值也会发送，因此可以清空电话、取消隐藏等
+type UpdateSharedContactReq struct {
+	ID          string  `json:"-"`                     // 联系人ID
+	Name        *string `json:"name,omitempty"`        // 姓名
+	FolderID    *string `json:"folderId,omitempty"`    // 所属分组ID
+	Email       *string `json:"email,omitempty"`       // 邮箱
+	WorkPhone   *string `json:"workPhone,omitempty"`   // 工作电话
+	Phone       *string `json:"phone,omitempty"`       // 手机号
+	HomeAddress *string `json:"homeAddress,omitempty"` // 家庭住址
+	CompanyName *string `json:"companyName,omitempty"` // 公司
+	JobTitle    *string `json:"jobTitle,omitempty"`    // 职位
+	WorkAddress *string `json:"workAddress,omitempty"` // 工作地址
+	Nickname    *string `json:"nickname,omitempty"`    // 昵称
+	Manager     *string `json:"manager,omitempty"`     // 上级
+	Info        *string `json:"info,omitempty"`        // 备注
+	IsHidden    *bool   `json:"isHidden,omitempty"`    // 是否隐藏
+}
+
+// Update 更新公共联系人
+func (s *SharedContactService) Update(ctx context.Context, req UpdateSharedContactReq) (*SharedContact, error) {
+	if req.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	if req.Name != nil && *req.Name == "" {
+		return nil, fmt.Errorf("name can't be empty")
+	}
+	if req.FolderID != nil && *req.FolderID == "" {
+		return nil, fmt.Errorf("folderId can't be empty")
+	}
+	path := fmt.Sprintf("/v2/sharedContacts/%s", req.ID)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := s.doRequest(ctx, MethodPatch, path, BaseHeader, body)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj SharedContact
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// Delete 删除公共联系人
+func (s *SharedContactService) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("id is required")
+	}
+	path := fmt.Sprintf("/v2/sharedContacts/%s", id)
+
+	resp, err := s.doRequest(ctx, MethodDelete, path, BaseHeader, nil)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
+
+// ListSharedContactsReq 分页获取分组下联系人的参数
+type ListSharedContactsReq struct {
+	FolderID string // 分组ID
+	Offset   int    // 分页偏移
+	Limit    int    // 分页大小，最大100
+}
+
+// ListSharedContactsRsp 联系人列表的返回
+type ListSharedContactsRsp struct {
+	Contacts []SharedContact `json:"contacts"`
+	Total    int             `json:"total"`
+}
+
+// ListByFolder 分页获取分组下的联系人
+func (s *SharedContactService) ListByFolder(ctx context.Context, req ListSharedContactsReq) (rst ListSharedContactsRsp, err error) {
+	if req.FolderID == "" {
+		return rst, fmt.Errorf("folderId is required")
+	}
+	if req.Limit > 100 {
+		return rst, fmt.Errorf("limit can't be more than 100")
+	}
+	path := fmt.Sprintf("/v2/sharedContactFolders/%s/contacts?offset=%d&limit=%d", req.FolderID, req.Offset, req.Limit)
+
+	resp, err := s.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return rst, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj ListSharedContactsRsp
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return rst, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return dataObj, nil
+	}
+	return rst, parseAPIError(resp)
+}
+
+// AllByFolder 遍历分组下的全部联系人（不含子分组），自动处理分页
+func (s *SharedContactService) AllByFolder(ctx context.Context, folderID string, opts ...PaginatorOption) iter.Seq2[SharedContact, error] {
+	return NewPaginator(func(ctx context.Context, offset, limit int) ([]SharedContact, int, error) {
+		rsp, err := s.ListByFolder(ctx, ListSharedContactsReq{FolderID: folderID, Offset: offset, Limit: limit})
+		return rsp.Contacts, rsp.Total, err
+	}, opts...).All(ctx)
+}
+
+// SearchSharedContactsReq 搜索联系人的参数
+type SearchSharedContactsReq struct {
+	Keyword string // 按姓名或邮箱匹配的关键字
+	Offset  int    // 分页偏移
+	Limit   int    // 分页大小，最大100
+}
+
+// Search 按姓名或邮箱搜索公共联系人
+func (s *SharedContactService) Search(ctx context.Context, req SearchSharedContactsReq) (rst ListSharedContactsRsp, err error) {
+	if req.Keyword == "" {
+		return rst, fmt.Errorf("keyword is required")
+	}
+	if req.Limit > 100 {
+		return rst, fmt.Errorf("limit can't be more than 100")
+	}
+	query := url.Values{}
+	query.Set("query", req.Keyword)
+	query.Set("offset", fmt.Sprint(req.Offset))
+	query.Set("limit", fmt.Sprint(req.Limit))
+	path := "/v2/sharedContacts/search?" + query.Encode()
+
+	resp, err := s.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return rst, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj ListSharedContactsRsp
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return rst, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return dataObj, nil
+	}
+	return rst, parseAPIError(resp)
+}
+
+// SharedContactResult 批量操作中单个联系人的结果
+type SharedContactResult struct {
+	Index   int            // 在请求列表中的下标
+	Contact *SharedContact // 成功时返回的联系人
+	Err     error          // 失败原因
+}
+
+// BatchCreate 批量创建公共联系人，单个失败不影响其它联系人
+func (s *SharedContactService) BatchCreate(ctx context.Context, reqs []SharedContactReq) []SharedContactResult {
+	results := make([]SharedContactResult, len(reqs))
+	for i, req := range reqs {
+		contact, err := s.Create(ctx, req)
+		results[i] = SharedContactResult{Index: i, Contact: contact, Err: err}
+	}
+	return results
+}
+
+// BatchUpdate 批量更新公共联系人，单个失败不影响其它联系人
+func (s *SharedContactService) BatchUpdate(ctx context.Context, reqs []UpdateSharedContactReq) []SharedContactResult {
+	results := make([]SharedContactResult, len(reqs))
+	for i, req := range reqs {
+		contact, err := s.Update(ctx, req)
+		results[i] = SharedContactResult{Index: i, Contact: contact, Err: err}
+	}
+	return results
+}
@@ -1,12 +1,20 @@
 package alimail
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
 	"time"
 )
 
 // SharedContactFolderService 公共联系人分组服务
 type SharedContactFolderService struct{ *Client }
 
+// RootSharedContactFolderID 公共联系人根分组ID
+const RootSharedContactFolderID = "$root"
+
 type SharedContactFolder struct {
 	ID                       string    `json:"id"`
 	Name                     string    `json:"name"`
@@ -18,3 +26,291 @@ type SharedContactFolder struct {
 	ContactCount             int64     `json:"contactCount"`
 	ChildFolderCount         int64     `json:"childFolderCount"`
 }
+
+// SharedContactFolderReq 创建分组的参数
+type SharedContactFolderReq struct {
+	Name                     string   `json:"name"`                     // 分组名称
+	ParentID                 string   `json:"parentId"`                 // 父分组ID，根分组为$root
+	IsHidden                 bool     `json:"isHidden"`                 // 是否隐藏
+	HiddenExcludeUsers       []string `json:"hiddenExcludeUsers"`       // 分组隐藏后，哪些白名单帐号 id 可访问该分组
+	HiddenExcludeDepartments []string `json:"hiddenExcludeDepartments"` // 分组隐藏后，哪些部门 id 可访问该分组
+}
+
+// Create 创建分组
+func (s *SharedContactFolderService) Create(ctx context.Context, req SharedContactFolderReq) (*SharedContactFolder, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if req.ParentID == "" {
+		req.ParentID = RootSharedContactFolderID
+	}
+	path := "/v2/sharedContactFolders"
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := s.doRequest(ctx, MethodPost, path, BaseHeader, body)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj SharedContactFolder
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// Get 获取分组信息
+func (s *SharedContactFolderService) Get(ctx context.Context, id string) (*SharedContactFolder, error) {
+	if id == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	path := fmt.Sprintf("/v2/sharedContactFolders/%s", id)
+
+	resp, err := s.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj SharedContactFolder
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &dataObj, nil
+	}
+	return nil, parseAPIError(resp)
+}
+
+// PatchSharedContactFolderReq 部分更新分组的参数，只发送非 nil 的字段，
+// 因此重命名不会影响分组的隐藏状态与白名单
+type PatchSharedContactFolderReq struct {
+	ID                       string    `json:"-"`                                  // 分组ID
+	Name                     *string   `json:"name,omitempty"`                     // 分组名称
+	ParentID                 *string   `json:"parentId,omitempty"`                 // 父分组ID，移动分组建议使用 Move
+	IsHidden                 *bool     `json:"isHidden,omitempty"`                 // 是否隐藏
+	HiddenExcludeUsers       *[]string `json:"hiddenExcludeUsers,omitempty"`       // 分组隐藏后，哪些白名单帐号 id 可访问该分组
+	HiddenExcludeDepartments *[]string `json:"hiddenExcludeDepartments,omitempty"` // 分组隐藏后，哪些部门 id 可访问该分组
+}
+
+// Update 部分更新分组信息
+func (s *SharedContactFolderService) Update(ctx context.Context, req PatchSharedContactFolderReq) error {
+	if req.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if req.Name != nil && *req.Name == "" {
+		return fmt.Errorf("name can't be empty")
+	}
+	if req.ParentID != nil && *req.ParentID == "" {
+		return fmt.Errorf("parentId can't be empty")
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return s.patch(ctx, req.ID, body)
+}
+
+func (s *SharedContactFolderService) patch(ctx context.Context, id string, body []byte) error {
+	path := fmt.Sprintf("/v2/sharedContactFolders/%s", id)
+
+	resp, err := s.doRequest(ctx, MethodPatch, path, BaseHeader, body)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
+
+// Delete 删除分组
+func (s *SharedContactFolderService) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("id is required")
+	}
+	path := fmt.Sprintf("/v2/sharedContactFolders/%s", id)
+
+	resp, err := s.doRequest(ctx, MethodDelete, path, BaseHeader, nil)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
+
+// ListSharedContactFoldersReq 分页获取子分组的参数
+type ListSharedContactFoldersReq struct {
+	ID     string // 父分组ID，根分组为$root
+	Offset int    // 分页偏移
+	Limit  int    // 分页大小，最大100
+}
+
+// ListSharedContactFoldersRsp 子分组列表的返回
+type ListSharedContactFoldersRsp struct {
+	Folders []SharedContactFolder `json:"folders"`
+	Total   int                   `json:"total"`
+}
+
+// ListChildren 分页获取子分组
+func (s *SharedContactFolderService) ListChildren(ctx context.Context, req ListSharedContactFoldersReq) (rst ListSharedContactFoldersRsp, err error) {
+	if req.ID == "" {
+		req.ID = RootSharedContactFolderID
+	}
+	if req.Limit > 100 {
+		return rst, fmt.Errorf("limit can't be more than 100")
+	}
+	path := fmt.Sprintf("/v2/sharedContactFolders/%s/folders?offset=%d&limit=%d", req.ID, req.Offset, req.Limit)
+
+	resp, err := s.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return rst, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj ListSharedContactFoldersRsp
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return rst, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return dataObj, nil
+	}
+	return rst, parseAPIError(resp)
+}
+
+// AllChildren 遍历分组下的全部直属子分组，自动处理分页
+func (s *SharedContactFolderService) AllChildren(ctx context.Context, id string, opts ...PaginatorOption) iter.Seq2[SharedContactFolder, error] {
+	return NewPaginator(func(ctx context.Context, offset, limit int) ([]SharedContactFolder, int, error) {
+		rsp, err := s.ListChildren(ctx, ListSharedContactFoldersReq{ID: id, Offset: offset, Limit: limit})
+		return rsp.Folders, rsp.Total, err
+	}, opts...).All(ctx)
+}
+
+// Move 将分组移动到新的父分组下，会在本地检查是否会形成环
+func (s *SharedContactFolderService) Move(ctx context.Context, id, parentID string) error {
+	if id == "" || parentID == "" {
+		return fmt.Errorf("id and parentId are required")
+	}
+	if id == parentID {
+		return fmt.Errorf("can't move folder %s into itself", id)
+	}
+	// 沿新父分组向上查找，如果经过自身说明目标是自己的子孙
+	for cur := parentID; cur != "" && cur != RootSharedContactFolderID; {
+		folder, err := s.Get(ctx, cur)
+		if err != nil {
+			return err
+		}
+		if folder.ParentID == id {
+			return fmt.Errorf("can't move folder %s under its descendant %s", id, parentID)
+		}
+		cur = folder.ParentID
+	}
+
+	body, err := json.Marshal(map[string]string{"parentId": parentID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return s.patch(ctx, id, body)
+}
+
+// SharedContactFolderNode 分组树中的节点
+type SharedContactFolderNode struct {
+	SharedContactFolder
+	Children []*SharedContactFolderNode
+}
+
+// Walk 先序遍历以该节点为根的子树，fn 返回 false 时不再深入该节点的子分组
+func (n *SharedContactFolderNode) Walk(fn func(node *SharedContactFolderNode, depth int) bool) {
+	n.walk(fn, 0)
+}
+
+func (n *SharedContactFolderNode) walk(fn func(*SharedContactFolderNode, int) bool, depth int) {
+	if !fn(n, depth) {
+		return
+	}
+	for _, c := range n.Children {
+		c.walk(fn, depth+1)
+	}
+}
+
+// Tree 从指定分组开始递归获取整棵分组树，rootID 为空时从根分组开始
+func (s *SharedContactFolderService) Tree(ctx context.Context, rootID string) (*SharedContactFolderNode, error) {
+	if rootID == "" {
+		rootID = RootSharedContactFolderID
+	}
+	root := &SharedContactFolderNode{SharedContactFolder: SharedContactFolder{ID: rootID}}
+	if rootID != RootSharedContactFolderID {
+		folder, err := s.Get(ctx, rootID)
+		if err != nil {
+			return nil, err
+		}
+		root.SharedContactFolder = *folder
+	}
+	if err := s.fillChildren(ctx, root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (s *SharedContactFolderService) fillChildren(ctx context.Context, node *SharedContactFolderNode) error {
+	for f, err := range s.AllChildren(ctx, node.ID) {
+		if err != nil {
+			return fmt.Errorf("list children of %s: %w", node.ID, err)
+		}
+		node.Children = append(node.Children, &SharedContactFolderNode{SharedContactFolder: f})
+	}
+	for _, child := range node.Children {
+		if child.ChildFolderCount == 0 {
+			continue
+		}
+		if err := s.fillChildren(ctx, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetFolderVisibilityReq 设置分组可见范围的参数
+type SetFolderVisibilityReq struct {
+	ID            string   // 分组ID
+	Hidden        bool     // 是否隐藏，隐藏后只有白名单可见
+	UserEmails    []string // 白名单用户邮箱，会被解析为帐号 id
+	DepartmentIDs []string // 白名单部门ID
+}
+
+// SetVisibility 设置分组是否隐藏以及隐藏后的白名单，白名单会整体覆盖原有设置
+func (s *SharedContactFolderService) SetVisibility(ctx context.Context, req SetFolderVisibilityReq) error {
+	if req.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	userIDs := make([]string, 0, len(req.UserEmails))
+	for _, email := range req.UserEmails {
+		user, err := s.User.Get(ctx, BaseUserReq{Email: email})
+		if err != nil {
+			return fmt.Errorf("resolve user %s: %w", email, err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	departmentIDs := req.DepartmentIDs
+	if departmentIDs == nil {
+		departmentIDs = []string{}
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"isHidden":                 req.Hidden,
+		"hiddenExcludeUsers":       userIDs,
+		"hiddenExcludeDepartments": departmentIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return s.patch(ctx, req.ID, body)
+}
@@ -0,0 +1,42 @@
+package alimail
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateSharedContactSendsOnlySetFields(t *testing.T) {
+	c, requests := newFakeServer(t, func(req recordedRequest) (int, any) {
+		return http.StatusOK, SharedContact{ID: "c1"}
+	})
+	_, err := c.SharedContact.Update(context.Background(), UpdateSharedContactReq{
+		ID:       "c1",
+		Phone:    Ptr(""),
+		IsHidden: Ptr(false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := requests()[0]
+	want := map[string]any{"phone": "", "isHidden": false}
+	if got.Method != MethodPatch || got.Path != "/v2/sharedContacts/c1" || !reflect.DeepEqual(got.Body, want) {
+		t.Errorf("request = %s %s %v, want body %v", got.Method, got.Path, got.Body, want)
+	}
+
+	if _, err := c.SharedContact.Update(context.Background(), UpdateSharedContactReq{ID: "c1", Name: Ptr("")}); err == nil {
+		t.Error("expected error for empty name")
+	}
+}
+
+func TestUpdateSharedContactFolderKeepsVisibility(t *testing.T) {
+	c, requests := newFakeServer(t)
+	if err := c.SharedContactFolder.Update(context.Background(), PatchSharedContactFolderReq{ID: "f1", Name: Ptr("财务")}); err != nil {
+		t.Fatal(err)
+	}
+	got := requests()[0]
+	if want := map[string]any{"name": "财务"}; got.Path != "/v2/sharedContactFolders/f1" || !reflect.DeepEqual(got.Body, want) {
+		t.Errorf("request = %s %v, want body %v", got.Path, got.Body, want)
+	}
+}
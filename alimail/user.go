@@ -4,7 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -64,6 +69,17 @@ type BaseUserReq struct {
 	Email string `json:"email"` // 用户邮箱
 }
 
+// path 返回用户相关接口的路径，优先使用邮箱
+func (r BaseUserReq) path(suffix string) (string, error) {
+	if r.Email == "" && r.ID == "" {
+		return "", fmt.Errorf("id and email can't be empty at the same time")
+	}
+	if r.Email != "" {
+		return fmt.Sprintf("/v2/users/%s%s", r.Email, suffix), nil
+	}
+	return fmt.Sprintf("/v2/users/%s%s", r.ID, suffix), nil
+}
+
 // Get 根据id或email获取用户信息,参数传入其一即可
 func (d *UserService) Get(ctx context.Context, req BaseUserReq) (*User, error) {
 	if req.Email == "" && req.ID == "" {
@@ -126,6 +142,12 @@ func (d *UserService) ListByIds(ctx context.Context, ids []string) ([]User, erro
 	return nil, parseAPIError(resp)
 }
 
+// ListAllByIds 根据任意数量的 id 批量获取帐号信息，内部按 100 个一组并发请求，
+// 返回结果与输入 id 的顺序一致（重复 id 只返回一次），不存在的 id 放入 notFound
+func (d *UserService) ListAllByIds(ctx context.Context, ids []string) (users []User, notFound []string, err error) {
+	return listByIdsChunked(ctx, ids, d.ListByIds, func(u User) string { return u.ID })
+}
+
 type CreateUserReq struct {
 	Email                         string             `json:"email"`                                   // 用户邮箱
 	Password                      string             `json:"password"`                                // 用户密码
@@ -148,9 +170,12 @@ type CreateUserReq struct {
 	EmployeeType                  EmailAccountType   `json:"employeeType,omitempty"`                  // 员工类型
 }
 
-// Create 创建用户
+// Create 创建用户，设置了密码策略时会先在本地校验密码
 func (u *UserService) Create(ctx context.Context, req CreateUserReq) (*User, error) {
 	path := "/v2/users"
+	if err := u.checkPassword(req.Password, PasswordContext{Name: req.Name, Email: req.Email}); err != nil {
+		return nil, err
+	}
 
 	headers := map[string]string{
 		"Content-Type": "application/json",
@@ -177,6 +202,7 @@ func (u *UserService) Create(ctx context.Context, req CreateUserReq) (*User, err
 	return nil, parseAPIError(resp)
 }
 
+// UpdateUserReq 更新用户的参数，会发送全部字段；只修改部分字段时请使用 Patch
 type UpdateUserReq struct {
 	BaseUserReq                                      // 用户ID或邮箱
 	Name                          string             `json:"name"`                                    // 用户姓名
@@ -257,6 +283,12 @@ type ChangeUserPasswordReq struct {
 	New string `json:"new"`
 }
 
+// changePasswordBody 修改密码接口的请求体
+type changePasswordBody struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
 // ChangePassword 修改用户密码
 func (d *UserService) ChangePassword(ctx context.Context, req ChangeUserPasswordReq) error {
 	if req.ID == "" && req.Email == "" {
@@ -268,13 +300,16 @@ func (d *UserService) ChangePassword(ctx context.Context, req ChangeUserPassword
 	} else {
 		path = fmt.Sprintf("/v2/users/%s/changePassword", req.ID)
 	}
+	if err := d.checkPassword(req.New, PasswordContext{Email: req.Email, OldPassword: req.Old}); err != nil {
+		return err
+	}
 
-	body, err := json.Marshal(map[string]string{"old": req.Old, "new": req.New})
+	body, err := json.Marshal(changePasswordBody{Old: req.Old, New: req.New})
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := d.doRequest(ctx, MethodDelete, path, BaseHeader, body)
+	resp, err := d.doRequest(ctx, MethodPost, path, BaseHeader, body)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
@@ -289,6 +324,12 @@ type ResetUserPasswordReq struct {
 	ForceChangePasswordNextSignIn bool   `json:"forceChangePasswordNextSignIn"` // 下次登录是否强制修改密码
 }
 
+// resetPasswordBody 重置密码接口的请求体
+type resetPasswordBody struct {
+	Password                      string `json:"password"`
+	ForceChangePasswordNextSignIn bool   `json:"forceChangePasswordNextSignIn"`
+}
+
 // ResetPassword 重置用户密码
 func (d *UserService) ResetPassword(ctx context.Context, req ResetUserPasswordReq) error {
 	if req.ID == "" && req.Email == "" {
@@ -300,10 +341,13 @@ func (d *UserService) ResetPassword(ctx context.Context, req ResetUserPasswordRe
 	} else {
 		path = fmt.Sprintf("/v2/users/%s/resetPassword", req.ID)
 	}
+	if err := d.checkPassword(req.Password, PasswordContext{Email: req.Email}); err != nil {
+		return err
+	}
 
-	body, err := json.Marshal(map[string]string{
-		"password":                      req.Password,
-		"forceChangePasswordNextSignIn": fmt.Sprintf("%t", req.ForceChangePasswordNextSignIn),
+	body, err := json.Marshal(resetPasswordBody{
+		Password:                      req.Password,
+		ForceChangePasswordNextSignIn: req.ForceChangePasswordNextSignIn,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
@@ -379,3 +423,216 @@ func (d *UserService) DeleteEmailAlias(ctx context.Context, req DeleteEmailAlias
 
 	return parseAPIError(resp)
 }
+
+// ListUsersReq 分页查询用户的参数，过滤条件为空时不生效
+type ListUsersReq struct {
+	Offset          int                // 分页偏移
+	Limit           int                // 分页大小，最大100
+	Keyword         string             // 按姓名、邮箱、员工编号模糊搜索
+	Status          EmailAccountStatus // 用户状态
+	EmployeeType    EmailAccountType   // 员工类型
+	DepartmentID    string             // 所属部门ID（仅直属）
+	IsHidden        *bool              // 是否隐藏
+	CreatedAfter    time.Time          // 创建时间不早于
+	CreatedBefore   time.Time          // 创建时间早于
+	LastLoginAfter  time.Time          // 最后登录时间不早于
+	LastLoginBefore time.Time          // 最后登录时间早于
+}
+
+func (req ListUsersReq) query() url.Values {
+	q := url.Values{}
+	q.Set("offset", strconv.Itoa(req.Offset))
+	q.Set("limit", strconv.Itoa(req.Limit))
+	if req.Keyword != "" {
+		q.Set("query", req.Keyword)
+	}
+	if req.Status != "" {
+		q.Set("status", string(req.Status))
+	}
+	if req.EmployeeType != "" {
+		q.Set("employeeType", string(req.EmployeeType))
+	}
+	if req.DepartmentID != "" {
+		q.Set("departmentId", req.DepartmentID)
+	}
+	if req.IsHidden != nil {
+		q.Set("isHidden", strconv.FormatBool(*req.IsHidden))
+	}
+	setTime := func(key string, t time.Time) {
+		if !t.IsZero() {
+			q.Set(key, t.Format(time.RFC3339))
+		}
+	}
+	setTime("createdTimeStart", req.CreatedAfter)
+	setTime("createdTimeEnd", req.CreatedBefore)
+	setTime("lastLoginTimeStart", req.LastLoginAfter)
+	setTime("lastLoginTimeEnd", req.LastLoginBefore)
+	return q
+}
+
+// Match 判断用户是否满足过滤条件（不含关键字），用于在本地对结果进行兜底过滤
+func (req ListUsersReq) Match(u User) bool {
+	if req.Status != "" && u.Status != req.Status {
+		return false
+	}
+	if req.EmployeeType != "" && u.EmployeeType != req.EmployeeType {
+		return false
+	}
+	if req.DepartmentID != "" && !slices.Contains(u.DepartmentIds, req.DepartmentID) {
+		return false
+	}
+	if req.IsHidden != nil && u.IsHidden != *req.IsHidden {
+		return false
+	}
+	if !req.CreatedAfter.IsZero() && u.CreatedTime.Before(req.CreatedAfter) {
+		return false
+	}
+	if !req.CreatedBefore.IsZero() && !u.CreatedTime.Before(req.CreatedBefore) {
+		return false
+	}
+	if !req.LastLoginAfter.IsZero() && u.LastLoginTime.Before(req.LastLoginAfter) {
+		return false
+	}
+	if !req.LastLoginBefore.IsZero() && !u.LastLoginTime.Before(req.LastLoginBefore) {
+		return false
+	}
+	return true
+}
+
+// ListUsersRsp 用户列表的返回
+type ListUsersRsp struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+}
+
+// List 分页查询组织内的用户
+func (d *UserService) List(ctx context.Context, req ListUsersReq) (rst ListUsersRsp, err error) {
+	if req.Limit > 100 {
+		return rst, fmt.Errorf("limit can't be more than 100")
+	}
+	path := "/v2/users?" + req.query().Encode()
+
+	resp, err := d.doRequest(ctx, MethodGet, path, BaseHeader, nil)
+	if err != nil {
+		return rst, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var dataObj ListUsersRsp
+		if err := json.NewDecoder(resp.Body).Decode(&dataObj); err != nil {
+			return rst, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return dataObj, nil
+	}
+	return rst, parseAPIError(resp)
+}
+
+// Seq 遍历满足条件的全部用户，req 中的 Offset 作为起始偏移，Limit 为每页大小（默认100）
+func (d *UserService) Seq(ctx context.Context, req ListUsersReq, opts ...PaginatorOption) iter.Seq2[User, error] {
+	return d.seq(ctx, req, nil, opts...)
+}
+
+// seq 在遍历时通过 onTotal 回传服务端报告的总数
+func (d *UserService) seq(ctx context.Context, req ListUsersReq, onTotal func(int), opts ...PaginatorOption) iter.Seq2[User, error] {
+	opts = append([]PaginatorOption{WithPageSize(req.Limit), WithStartOffset(req.Offset)}, opts...)
+	p := NewPaginator(func(ctx context.Context, offset, limit int) ([]User, int, error) {
+		r := req
+		r.Offset, r.Limit = offset, limit
+		rsp, err := d.List(ctx, r)
+		if err == nil && onTotal != nil {
+			onTotal(rsp.Total)
+		}
+		return rsp.Users, rsp.Total, err
+	}, opts...)
+	return func(yield func(User, error) bool) {
+		for u, err := range p.All(ctx) {
+			if err == nil && !req.Match(u) {
+				continue
+			}
+			if !yield(u, err) {
+				return
+			}
+		}
+	}
+}
+
+// UserIterator 逐个遍历查询结果，自动处理分页
+type UserIterator struct {
+	svc   *UserService
+	req   ListUsersReq
+	next  func() (User, error, bool)
+	stop  func()
+	cur   User
+	total int
+	err   error
+	done  bool
+}
+
+// Iter 返回满足条件的用户迭代器，适用于不方便使用 range-over-func 的场景。
+// 没有遍历到末尾就退出时必须调用 Close，否则后台的分页协程和进行中的请求不会结束。
+//
+//	it := client.User.Iter(alimail.ListUsersReq{Status: alimail.NORMAL})
+//	defer it.Close()
+//	for it.Next(ctx) {
+//		u := it.User()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+func (d *UserService) Iter(req ListUsersReq) *UserIterator {
+	return &UserIterator{svc: d, req: req}
+}
+
+// Next 前进到下一个用户，没有更多用户或出错时返回 false。
+// 第一次调用时传入的 ctx 会用于整个遍历过程。
+func (it *UserIterator) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	if it.next == nil {
+		it.next, it.stop = iter.Pull2(it.svc.seq(ctx, it.req, func(total int) { it.total = total }))
+	}
+	u, err, ok := it.next()
+	if !ok || err != nil {
+		it.err = err
+		it.Close()
+		return false
+	}
+	it.cur = u
+	return true
+}
+
+// Close 结束遍历并释放后台资源，之后 Next 总是返回 false，可以重复调用
+func (it *UserIterator) Close() {
+	it.done = true
+	if it.stop != nil {
+		it.stop()
+	}
+}
+
+// User 返回当前用户
+func (it *UserIterator) User() User { return it.cur }
+
+// Err 返回遍历过程中的错误
+func (it *UserIterator) Err() error { return it.err }
+
+// Total 返回服务端报告的总数，在第一次调用 Next 之前为 0
+func (it *UserIterator) Total() int { return it.total }
+
+// All 获取满足条件的全部用户
+func (d *UserService) All(ctx context.Context, req ListUsersReq) ([]User, error) {
+	return collectSeq(d.Seq(ctx, req))
+}
+
+// Search 按关键字在姓名、邮箱、员工编号中模糊搜索用户，返回全部匹配的用户，
+// req 中的其它过滤条件同样生效
+//
+//	users, err := client.User.Search(ctx, "张", alimail.ListUsersReq{Status: alimail.NORMAL})
+func (d *UserService) Search(ctx context.Context, keyword string, req ListUsersReq) ([]User, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, fmt.Errorf("keyword is required")
+	}
+	req.Keyword = keyword
+	return d.All(ctx, req)
+}
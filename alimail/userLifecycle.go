@@ -0,0 +1,151 @@
+package alimail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// UserLifecycleAction 帐号生命周期操作
+type UserLifecycleAction string
+
+// 帐号生命周期操作类型
+const (
+	ActionFreeze   UserLifecycleAction = "freeze"   // 冻结
+	ActionUnfreeze UserLifecycleAction = "unfreeze" // 解冻
+	ActionHide     UserLifecycleAction = "hide"     // 在通讯录中隐藏
+	ActionUnhide   UserLifecycleAction = "unhide"   // 取消隐藏
+)
+
+// body 返回该操作只修改对应字段的请求体，避免带上其它字段覆盖用户信息
+func (a UserLifecycleAction) body() (map[string]any, error) {
+	switch a {
+	case ActionFreeze:
+		return map[string]any{"status": FREEZE}, nil
+	case ActionUnfreeze:
+		return map[string]any{"status": NORMAL}, nil
+	case ActionHide:
+		return map[string]any{"isHidden": true}, nil
+	case ActionUnhide:
+		return map[string]any{"isHidden": false}, nil
+	}
+	return nil, fmt.Errorf("unknown lifecycle action %q", a)
+}
+
+// UserLifecycleReq 帐号生命周期操作的参数
+type UserLifecycleReq struct {
+	BaseUserReq
+	Reason string // 操作原因，必填，会随结果返回并传给 SetLifecycleAudit 设置的审计回调
+}
+
+// UserLifecycleResult 单个帐号的操作结果
+type UserLifecycleResult struct {
+	BaseUserReq
+	Action UserLifecycleAction // 执行的操作
+	Reason string              // 操作原因
+	Err    error               // 失败原因，为空表示成功
+}
+
+// Freeze 冻结帐号，冻结后无法登录和收发邮件
+func (u *UserService) Freeze(ctx context.Context, req UserLifecycleReq) error {
+	return u.lifecycle(ctx, ActionFreeze, req)
+}
+
+// Unfreeze 解冻帐号
+func (u *UserService) Unfreeze(ctx context.Context, req UserLifecycleReq) error {
+	return u.lifecycle(ctx, ActionUnfreeze, req)
+}
+
+// Hide 在通讯录中隐藏帐号
+func (u *UserService) Hide(ctx context.Context, req UserLifecycleReq) error {
+	return u.lifecycle(ctx, ActionHide, req)
+}
+
+// Unhide 取消在通讯录中隐藏帐号
+func (u *UserService) Unhide(ctx context.Context, req UserLifecycleReq) error {
+	return u.lifecycle(ctx, ActionUnhide, req)
+}
+
+// lifecycle 执行操作，并把原因与结果交给审计回调
+func (u *UserService) lifecycle(ctx context.Context, action UserLifecycleAction, req UserLifecycleReq) error {
+	err := u.applyLifecycle(ctx, action, req)
+	if u.lifecycleAudit != nil {
+		u.lifecycleAudit(ctx, UserLifecycleResult{BaseUserReq: req.BaseUserReq, Action: action, Reason: req.Reason, Err: err})
+	}
+	return err
+}
+
+func (u *UserService) applyLifecycle(ctx context.Context, action UserLifecycleAction, req UserLifecycleReq) error {
+	if req.Email == "" && req.ID == "" {
+		return fmt.Errorf("id and email can't be empty at the same time")
+	}
+	if req.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	fields, err := action.body()
+	if err != nil {
+		return err
+	}
+	var path string
+	if req.Email != "" {
+		path = fmt.Sprintf("/v2/users/%s", req.Email)
+	} else {
+		path = fmt.Sprintf("/v2/users/%s", req.ID)
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := u.doRequest(ctx, MethodPatch, path, BaseHeader, body)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return parseAPIError(resp)
+}
+
+// lifecycleConcurrency 批量操作的并发数，实际速率仍受全局限速器约束
+const lifecycleConcurrency = 10
+
+// BatchLifecycle 并发对多个帐号执行同一操作，结果与输入顺序一致，单个失败不影响其它帐号
+func (u *UserService) BatchLifecycle(ctx context.Context, action UserLifecycleAction, reqs []UserLifecycleReq) []UserLifecycleResult {
+	results := make([]UserLifecycleResult, len(reqs))
+	sem := make(chan struct{}, lifecycleConcurrency)
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		results[i] = UserLifecycleResult{BaseUserReq: req.BaseUserReq, Action: action, Reason: req.Reason}
+		wg.Add(1)
+		go func(i int, req UserLifecycleReq) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i].Err = u.lifecycle(ctx, action, req)
+		}(i, req)
+	}
+	wg.Wait()
+	return results
+}
+
+// BatchFreeze 批量冻结帐号
+func (u *UserService) BatchFreeze(ctx context.Context, reqs []UserLifecycleReq) []UserLifecycleResult {
+	return u.BatchLifecycle(ctx, ActionFreeze, reqs)
+}
+
+// BatchUnfreeze 批量解冻帐号
+func (u *UserService) BatchUnfreeze(ctx context.Context, reqs []UserLifecycleReq) []UserLifecycleResult {
+	return u.BatchLifecycle(ctx, ActionUnfreeze, reqs)
+}
+
+// BatchHide 批量隐藏帐号
+func (u *UserService) BatchHide(ctx context.Context, reqs []UserLifecycleReq) []UserLifecycleResult {
+	return u.BatchLifecycle(ctx, ActionHide, reqs)
+}
+
+// BatchUnhide 批量取消隐藏帐号
+func (u *UserService) BatchUnhide(ctx context.Context, reqs []UserLifecycleReq) []UserLifecycleResult {
+	return u.BatchLifecycle(ctx, ActionUnhide, reqs)
+}
@@ -0,0 +1,54 @@
+package alimail
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestLifecycleAuditRecordsReason(t *testing.T) {
+	c, requests := newFakeServer(t)
+	var (
+		mu      sync.Mutex
+		audited []string
+	)
+	c.SetLifecycleAudit(func(ctx context.Context, r UserLifecycleResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		entry := string(r.Action) + " " + r.Email + " " + r.Reason
+		if r.Err != nil {
+			entry += " " + r.Err.Error()
+		}
+		audited = append(audited, entry)
+	})
+	ctx := context.Background()
+	if err := c.User.Freeze(ctx, UserLifecycleReq{BaseUserReq: BaseUserReq{Email: "a@example.com"}, Reason: "compromised"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.User.Hide(ctx, UserLifecycleReq{BaseUserReq: BaseUserReq{Email: "b@example.com"}}); err == nil {
+		t.Error("expected error for missing reason")
+	}
+	results := c.User.BatchUnfreeze(ctx, []UserLifecycleReq{
+		{BaseUserReq: BaseUserReq{Email: "c@example.com"}, Reason: "restored"},
+		{BaseUserReq: BaseUserReq{Email: "d@example.com"}, Reason: "restored"},
+	})
+	for _, r := range results {
+		if r.Err != nil || r.Reason != "restored" {
+			t.Errorf("result = %+v", r)
+		}
+	}
+	slices.Sort(audited)
+	want := []string{
+		"freeze a@example.com compromised",
+		"hide b@example.com  reason is required",
+		"unfreeze c@example.com restored",
+		"unfreeze d@example.com restored",
+	}
+	if !slices.Equal(audited, want) {
+		t.Errorf("audited = %q, want %q", audited, want)
+	}
+	if n := len(requests()); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
@@ -0,0 +1,238 @@
+package alimail
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   map[string]any
+}
+
+// fakeResponder 根据请求返回状态码与 JSON 响应体，状态码为 0 时返回 200 与空响应体
+type fakeResponder func(req recordedRequest) (int, any)
+
+// newFakeServer 启动一个本地假服务，颁发 token 并记录所有接口请求，可选的 respond 决定接口的返回
+func newFakeServer(t *testing.T, respond ...fakeResponder) (*Client, func() []recordedRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []recordedRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == tokenPath {
+			json.NewEncoder(w).Encode(TokenResponse{TokenType: "bearer", AccessToken: "test-token", ExpiresIn: 3600})
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "bearer test-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		data, _ := io.ReadAll(r.Body)
+		rec := recordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query()}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &rec.Body); err != nil {
+				t.Errorf("invalid json body %q: %v", data, err)
+			}
+		}
+		mu.Lock()
+		reqs = append(reqs, rec)
+		mu.Unlock()
+		for _, fn := range respond {
+			if status, body := fn(rec); status != 0 {
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(body)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient("id", "secret")
+	c.SetBaseURL(srv.URL)
+	return c, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), reqs...)
+	}
+}
+
+func TestResetPasswordSendsBoolean(t *testing.T) {
+	c, requests := newFakeServer(t)
+	err := c.User.ResetPassword(context.Background(), ResetUserPasswordReq{
+		BaseUserReq:                   BaseUserReq{Email: "a@example.com"},
+		Password:                      "Abcdef12!x",
+		ForceChangePasswordNextSignIn: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	got := reqs[0]
+	if got.Method != MethodPost || got.Path != "/v2/users/a@example.com/resetPassword" {
+		t.Errorf("request = %s %s", got.Method, got.Path)
+	}
+	if v, ok := got.Body["forceChangePasswordNextSignIn"].(bool); !ok || !v {
+		t.Errorf("forceChangePasswordNextSignIn = %#v, want true", got.Body["forceChangePasswordNextSignIn"])
+	}
+	if got.Body["password"] != "Abcdef12!x" {
+		t.Errorf("password = %#v", got.Body["password"])
+	}
+}
+
+func TestChangePasswordUsesPost(t *testing.T) {
+	c, requests := newFakeServer(t)
+	err := c.User.ChangePassword(context.Background(), ChangeUserPasswordReq{
+		BaseUserReq: BaseUserReq{ID: "u1"},
+		Old:         "Old12345!a",
+		New:         "New12345!b",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := requests()[0]
+	if got.Method != MethodPost || got.Path != "/v2/users/u1/changePassword" {
+		t.Errorf("request = %s %s", got.Method, got.Path)
+	}
+	if got.Body["old"] != "Old12345!a" || got.Body["new"] != "New12345!b" {
+		t.Errorf("body = %#v", got.Body)
+	}
+}
+
+func TestPasswordPolicyCheckedLocally(t *testing.T) {
+	c, requests := newFakeServer(t)
+	req := ResetUserPasswordReq{BaseUserReq: BaseUserReq{Email: "a@example.com"}, Password: "short"}
+	// 默认不在本地校验，交由服务端判断
+	if err := c.User.ResetPassword(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(requests()); n != 1 {
+		t.Fatalf("got %d requests, want 1", n)
+	}
+
+	c.SetPasswordPolicy(DefaultPasswordPolicy)
+	err := c.User.ResetPassword(context.Background(), req)
+	if _, ok := err.(*PasswordPolicyError); !ok {
+		t.Fatalf("err = %v, want *PasswordPolicyError", err)
+	}
+	if n := len(requests()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestPasswordPolicyWhitespaceReportedOnce(t *testing.T) {
+	err := PasswordPolicy{}.Check("a b c d", PasswordContext{})
+	pe, ok := err.(*PasswordPolicyError)
+	if !ok {
+		t.Fatalf("err = %v, want *PasswordPolicyError", err)
+	}
+	if !reflect.DeepEqual(pe.Reasons, []string{"must not contain whitespace"}) {
+		t.Errorf("reasons = %q", pe.Reasons)
+	}
+}
+
+func TestResetPasswordsWritesPrivateFile(t *testing.T) {
+	c, requests := newFakeServer(t)
+	out := filepath.Join(t.TempDir(), "passwords.csv")
+	results, err := c.User.ResetPasswords(context.Background(), ResetPasswordsReq{
+		Users:                         []BaseUserReq{{Email: "a@example.com"}, {Email: "b@example.com"}},
+		ForceChangePasswordNextSignIn: true,
+		OutputFile:                    out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Err != nil || r.Password != "" {
+			t.Errorf("result = %+v", r)
+		}
+	}
+
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("perm = %o, want 600", perm)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	reqs := requests()
+	if len(lines) != 2 || len(reqs) != 2 {
+		t.Fatalf("got %d lines and %d requests, want 2", len(lines), len(reqs))
+	}
+	for i, line := range lines {
+		account, pwd, _ := strings.Cut(line, ",")
+		if account != reqs[i].Path[len("/v2/users/"):len(reqs[i].Path)-len("/resetPassword")] {
+			t.Errorf("line %d account = %q, request path %s", i, account, reqs[i].Path)
+		}
+		if reqs[i].Body["password"] != pwd {
+			t.Errorf("line %d password doesn't match request", i)
+		}
+	}
+
+	if _, err := c.User.ResetPasswords(context.Background(), ResetPasswordsReq{OutputFile: out}); err == nil {
+		t.Error("expected error when output file already exists")
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	c, requests := newFakeServer(t, func(req recordedRequest) (int, any) {
+		return http.StatusOK, ListUsersRsp{Users: []User{{Email: "zhang@example.com", Status: NORMAL}, {Email: "zhao@example.com", Status: FREEZE}}, Total: 2}
+	})
+	users, err := c.User.Search(context.Background(), " zh ", ListUsersReq{Status: NORMAL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Email != "zhang@example.com" {
+		t.Errorf("users = %+v", users)
+	}
+	q := requests()[0].Query
+	if q.Get("query") != "zh" || q.Get("status") != string(NORMAL) {
+		t.Errorf("query = %v", q)
+	}
+	if _, err := c.User.Search(context.Background(), "", ListUsersReq{}); err == nil {
+		t.Error("expected error for empty keyword")
+	}
+}
+
+func TestUserIteratorClose(t *testing.T) {
+	c, requests := newFakeServer(t, func(req recordedRequest) (int, any) {
+		return http.StatusOK, ListUsersRsp{Users: []User{{Email: req.Query.Get("offset") + "@example.com"}}, Total: 3}
+	})
+	it := c.User.Iter(ListUsersReq{Limit: 1})
+	if !it.Next(context.Background()) || it.User().Email != "0@example.com" {
+		t.Fatalf("first user = %+v, err %v", it.User(), it.Err())
+	}
+	it.Close()
+	it.Close()
+	if it.Next(context.Background()) {
+		t.Error("Next after Close returned true")
+	}
+	if it.Err() != nil {
+		t.Errorf("err = %v", it.Err())
+	}
+	n := len(requests())
+	if n > 2 {
+		t.Errorf("got %d page requests after closing on the first user", n)
+	}
+}
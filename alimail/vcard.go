@@ -0,0 +1,358 @@
+package alimail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// vCard 3.0/4.0 与公共联系人之间的互相转换
+
+// vCard 版本
+const (
+	VCardVersion3 = "3.0"
+	VCardVersion4 = "4.0"
+)
+
+// WriteVCard 将联系人写为 vCard，多个联系人依次写入同一个文件
+func WriteVCard(w io.Writer, contacts []SharedContact, version string) error {
+	if version == "" {
+		version = VCardVersion3
+	}
+	if version != VCardVersion3 && version != VCardVersion4 {
+		return fmt.Errorf("unsupported vCard version %q", version)
+	}
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	for _, c := range contacts {
+		writeVCardEntry(iw, c, version)
+	}
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+func writeVCardEntry(iw *icsWriter, c SharedContact, version string) {
+	typ := func(t string) string {
+		if version == VCardVersion4 {
+			return ";TYPE=" + strings.ToLower(t)
+		}
+		return ";TYPE=" + strings.ToUpper(t)
+	}
+	tel := func(kind, number string) {
+		if version == VCardVersion4 {
+			iw.line("TEL;VALUE=uri" + typ(kind) + ":tel:" + number)
+			return
+		}
+		iw.line("TEL" + typ(kind) + ":" + number)
+	}
+
+	iw.line("BEGIN:VCARD")
+	iw.line("VERSION:" + version)
+	iw.line("FN:" + icsEscape(c.Name))
+	iw.line("N:" + icsEscape(c.Name) + ";;;;")
+	if c.Nickname != "" {
+		iw.line("NICKNAME:" + icsEscape(c.Nickname))
+	}
+	if c.Email != "" {
+		iw.line("EMAIL" + typ("work") + ":" + c.Email)
+	}
+	if c.Phone != "" {
+		tel("cell", c.Phone)
+	}
+	if c.WorkPhone != "" {
+		tel("work", c.WorkPhone)
+	}
+	if c.CompanyName != "" {
+		iw.line("ORG:" + icsEscape(c.CompanyName))
+	}
+	if c.JobTitle != "" {
+		iw.line("TITLE:" + icsEscape(c.JobTitle))
+	}
+	if c.WorkAddress != "" {
+		iw.line("ADR" + typ("work") + ":;;" + icsEscape(c.WorkAddress) + ";;;;")
+	}
+	if c.HomeAddress != "" {
+		iw.line("ADR" + typ("home") + ":;;" + icsEscape(c.HomeAddress) + ";;;;")
+	}
+	if c.Info != "" {
+		iw.line("NOTE:" + icsEscape(c.Info))
+	}
+	if c.ID != "" {
+		iw.line("UID:" + icsEscape(c.ID))
+	}
+	iw.line("END:VCARD")
+}
+
+// vcardTypes 汇总 TYPE 参数，兼容 TYPE=work,voice、多个 TYPE 以及 2.1 的裸参数写法
+func vcardTypes(line string) map[string]bool {
+	types := map[string]bool{}
+	head := line
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		head = line[:i]
+	}
+	for _, f := range strings.Split(head, ";")[1:] {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			types[strings.ToLower(k)] = true
+			continue
+		}
+		if strings.EqualFold(k, "TYPE") {
+			for _, t := range strings.Split(strings.Trim(v, `"`), ",") {
+				types[strings.ToLower(t)] = true
+			}
+		}
+	}
+	return types
+}
+
+// splitVCardValue 按未转义的分号切分结构化取值
+func splitVCardValue(v string) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '\\' && i+1 < len(v):
+			b.WriteByte(v[i])
+			b.WriteByte(v[i+1])
+			i++
+		case v[i] == ';':
+			parts = append(parts, icsUnescape(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return append(parts, icsUnescape(b.String()))
+}
+
+// joinNonEmpty 拼接结构化取值中非空的部分
+func joinNonEmpty(parts []string, sep string) string {
+	var list []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	return strings.Join(list, sep)
+}
+
+// ParseVCard 解析 vCard 文件中的全部联系人，支持 3.0 与 4.0
+func ParseVCard(r io.Reader) ([]SharedContact, error) {
+	lines, err := readICSLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vCard: %w", err)
+	}
+	var (
+		contacts []SharedContact
+		cur      *SharedContact
+	)
+	for _, l := range lines {
+		p, err := parseICSProp(l)
+		if err != nil {
+			return nil, err
+		}
+		// 去掉 item1.EMAIL 这类分组前缀
+		if i := strings.LastIndexByte(p.Name, '.'); i >= 0 {
+			p.Name = p.Name[i+1:]
+		}
+		switch {
+		case p.Name == "BEGIN" && strings.EqualFold(p.Value, "VCARD"):
+			cur = &SharedContact{}
+			continue
+		case p.Name == "END" && strings.EqualFold(p.Value, "VCARD"):
+			if cur != nil {
+				contacts = append(contacts, *cur)
+			}
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		types := vcardTypes(l)
+		switch p.Name {
+		case "FN":
+			cur.Name = icsUnescape(p.Value)
+		case "N":
+			if cur.Name == "" {
+				parts := splitVCardValue(p.Value)
+				// N 的顺序为 姓;名;中间名;前缀;后缀
+				if len(parts) > 1 {
+					parts[0], parts[1] = parts[1], parts[0]
+				}
+				cur.Name = joinNonEmpty(parts, " ")
+			}
+		case "NICKNAME":
+			cur.Nickname = icsUnescape(p.Value)
+		case "EMAIL":
+			if cur.Email == "" || types["pref"] {
+				cur.Email = strings.TrimPrefix(p.Value, "mailto:")
+			}
+		case "TEL":
+			number := strings.TrimPrefix(p.Value, "tel:")
+			switch {
+			case types["cell"] || types["mobile"]:
+				cur.Phone = number
+			case types["work"]:
+				cur.WorkPhone = number
+			case cur.Phone == "":
+				cur.Phone = number
+			}
+		case "ORG":
+			cur.CompanyName = joinNonEmpty(splitVCardValue(p.Value), " ")
+		case "TITLE":
+			cur.JobTitle = icsUnescape(p.Value)
+		case "ADR":
+			addr := joinNonEmpty(splitVCardValue(p.Value), " ")
+			if types["home"] {
+				cur.HomeAddress = addr
+			} else {
+				cur.WorkAddress = addr
+			}
+		case "NOTE":
+			cur.Info = icsUnescape(p.Value)
+		}
+	}
+	return contacts, nil
+}
+
+// toReq 将联系人转为创建参数
+func (c SharedContact) toReq(folderID string) SharedContactReq {
+	return SharedContactReq{
+		Name:        c.Name,
+		FolderID:    folderID,
+		Email:       c.Email,
+		WorkPhone:   c.WorkPhone,
+		Phone:       c.Phone,
+		HomeAddress: c.HomeAddress,
+		CompanyName: c.CompanyName,
+		JobTitle:    c.JobTitle,
+		WorkAddress: c.WorkAddress,
+		Nickname:    c.Nickname,
+		Manager:     c.Manager,
+		Info:        c.Info,
+		IsHidden:    c.IsHidden,
+	}
+}
+
+// toUpdateReq 将联系人中有值的字段转为更新参数，vCard 中没有的字段以及分组、隐藏状态保持不变
+func (c SharedContact) toUpdateReq(id string) UpdateSharedContactReq {
+	nonEmpty := func(v string) *string {
+		if v == "" {
+			return nil
+		}
+		return &v
+	}
+	return UpdateSharedContactReq{
+		ID:          id,
+		Name:        nonEmpty(c.Name),
+		Email:       nonEmpty(c.Email),
+		WorkPhone:   nonEmpty(c.WorkPhone),
+		Phone:       nonEmpty(c.Phone),
+		HomeAddress: nonEmpty(c.HomeAddress),
+		CompanyName: nonEmpty(c.CompanyName),
+		JobTitle:    nonEmpty(c.JobTitle),
+		WorkAddress: nonEmpty(c.WorkAddress),
+		Nickname:    nonEmpty(c.Nickname),
+		Manager:     nonEmpty(c.Manager),
+		Info:        nonEmpty(c.Info),
+	}
+}
+
+// ImportVCardReq 导入 vCard 的参数
+type ImportVCardReq struct {
+	FolderID       string    // 导入到的分组ID
+	Reader         io.Reader // vCard 内容
+	UpdateExisting bool      // 邮箱已存在时是否用 vCard 内容更新，默认跳过
+}
+
+// ImportVCardResult 单个联系人的导入结果
+type ImportVCardResult struct {
+	Contact SharedContact  // vCard 中解析出的联系人
+	Result  *SharedContact // 创建或更新后的联系人
+	Skipped bool           // 因邮箱重复被跳过
+	Err     error          // 失败原因
+}
+
+// ImportVCard 将 vCard 中的联系人导入到分组，按邮箱（不区分大小写）去重，
+// 包括与分组中已有联系人以及文件内部的重复
+func (s *SharedContactService) ImportVCard(ctx context.Context, req ImportVCardReq) ([]ImportVCardResult, error) {
+	if req.FolderID == "" {
+		return nil, fmt.Errorf("folderId is required")
+	}
+	contacts, err := ParseVCard(req.Reader)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.listAllByFolder(ctx, req.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	byEmail := make(map[string]string, len(existing))
+	for _, c := range existing {
+		if c.Email != "" {
+			byEmail[strings.ToLower(c.Email)] = c.ID
+		}
+	}
+
+	results := make([]ImportVCardResult, 0, len(contacts))
+	for _, c := range contacts {
+		res := ImportVCardResult{Contact: c}
+		key := strings.ToLower(c.Email)
+		id, dup := byEmail[key]
+		switch {
+		case c.Name == "":
+			res.Err = fmt.Errorf("contact without name")
+		case dup && (!req.UpdateExisting || id == ""):
+			res.Skipped = true
+		case dup:
+			res.Result, res.Err = s.Update(ctx, c.toUpdateReq(id))
+		default:
+			res.Result, res.Err = s.Create(ctx, c.toReq(req.FolderID))
+			if res.Err == nil && key != "" {
+				byEmail[key] = res.Result.ID
+			}
+		}
+		results = append(results, res)
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+// ExportVCard 导出分组及其所有子分组中的联系人到同一个 vCard 文件
+func (s *SharedContactService) ExportVCard(ctx context.Context, folderID string, w io.Writer, version string) error {
+	tree, err := s.SharedContactFolder.Tree(ctx, folderID)
+	if err != nil {
+		return err
+	}
+	var (
+		all     []SharedContact
+		walkErr error
+	)
+	tree.Walk(func(node *SharedContactFolderNode, _ int) bool {
+		if walkErr != nil {
+			return false
+		}
+		contacts, err := s.listAllByFolder(ctx, node.ID)
+		if err != nil {
+			walkErr = err
+			return false
+		}
+		all = append(all, contacts...)
+		return true
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return WriteVCard(w, all, version)
+}
+
+// listAllByFolder 获取分组下的全部联系人，不包含子分组
+func (s *SharedContactService) listAllByFolder(ctx context.Context, folderID string) ([]SharedContact, error) {
+	return collectSeq(s.AllByFolder(ctx, folderID))
+}
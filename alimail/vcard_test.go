@@ -0,0 +1,127 @@
+package alimail
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVCardRoundTrip(t *testing.T) {
+	contacts := []SharedContact{
+		{
+			Name:        "张三",
+			Nickname:    "老张",
+			Email:       "zhangsan@example.com",
+			Phone:       "+86 138 0000 0000",
+			WorkPhone:   "010-12345678",
+			CompanyName: "示例科技, 北京分公司",
+			JobTitle:    "工程师; 架构组",
+			WorkAddress: "北京市海淀区中关村大街 1 号",
+			HomeAddress: "上海市浦东新区",
+			Info:        strings.Repeat("备注内容很长，需要折行。", 8) + "\n第二行",
+		},
+		{Name: "Li Si", Email: "lisi@example.com"},
+	}
+	for _, version := range []string{VCardVersion3, VCardVersion4} {
+		var buf bytes.Buffer
+		if err := WriteVCard(&buf, contacts, version); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range strings.Split(buf.String(), "\r\n") {
+			if len(l) > 75 {
+				t.Errorf("%s: line not folded: %q", version, l)
+			}
+		}
+		got, err := ParseVCard(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, contacts) {
+			t.Errorf("%s round trip mismatch\n got %+v\nwant %+v", version, got, contacts)
+		}
+	}
+}
+
+func TestWriteVCard4(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteVCard(&buf, []SharedContact{{ID: "c1", Name: "王五", Phone: "13800000000", Email: "w@example.com"}}, VCardVersion4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"FN:王五",
+		"N:王五;;;;",
+		"EMAIL;TYPE=work:w@example.com",
+		"TEL;VALUE=uri;TYPE=cell:tel:13800000000",
+		"UID:c1",
+		"END:VCARD",
+		"",
+	}, "\r\n")
+	if buf.String() != want {
+		t.Errorf("vCard =\n%s\nwant\n%s", buf.String(), want)
+	}
+	if err := WriteVCard(&buf, nil, "2.1"); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func TestParseVCard(t *testing.T) {
+	// 3.0：折行、item1. 分组前缀、TYPE=work,voice、多个 TYPE 参数与 2.1 的裸参数
+	v3 := strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"N:Zhang;San;;;",
+		"item1.EMAIL;TYPE=INTERNET:other@example.com",
+		"item1.X-ABLabel:other",
+		"EMAIL;TYPE=INTERNET;TYPE=PREF:zs@example.com",
+		"TEL;TYPE=WORK,VOICE:010-1234",
+		"TEL;CELL:138",
+		"ORG:示例科技;研发部",
+		"ADR;TYPE=HOME:;;长安街 1 号;北京;;100000;中国",
+		"NOTE:第一段，",
+		" 折行后的第二段",
+		"END:VCARD",
+		// 4.0：带引号的 TYPE、tel: URI，没有 TYPE 的电话作为手机
+		"BEGIN:VCARD",
+		"VERSION:4.0",
+		"FN:Li Si",
+		`TEL;VALUE=uri;TYPE="cell,voice":tel:+86-139`,
+		"EMAIL:mailto:lisi@example.com",
+		"ADR:;;望京 SOHO;;;;",
+		"END:VCARD",
+		"BEGIN:VCARD",
+		"FN:Wang Wu",
+		"TEL:010-0000",
+		"END:VCARD",
+	}, "\r\n")
+	got, err := ParseVCard(strings.NewReader(v3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SharedContact{
+		{
+			Name:        "San Zhang",
+			Email:       "zs@example.com",
+			WorkPhone:   "010-1234",
+			Phone:       "138",
+			CompanyName: "示例科技 研发部",
+			HomeAddress: "长安街 1 号 北京 100000 中国",
+			Info:        "第一段，折行后的第二段",
+		},
+		{Name: "Li Si", Email: "lisi@example.com", Phone: "+86-139", WorkAddress: "望京 SOHO"},
+		{Name: "Wang Wu", Phone: "010-0000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("contacts =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestVCardUpdateKeepsMissingFields(t *testing.T) {
+	req := SharedContact{Name: "张三", Phone: "13800000000"}.toUpdateReq("c1")
+	if req.Name == nil || *req.Name != "张三" || req.Phone == nil || req.Email != nil || req.IsHidden != nil || req.FolderID != nil {
+		t.Errorf("update req = %+v", req)
+	}
+}
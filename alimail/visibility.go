@@ -0,0 +1,311 @@
+package alimail
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// 隐藏部门与隐藏用户的可见范围审计
+//
+// 按以下规则计算可见性：
+//   - 部门自身或任一上级部门 IsHidden 时，该部门处于隐藏状态；
+//   - 对路径上每一个隐藏的部门 H，查看者满足以下任一条件才算通过：
+//     是 H 或其下级部门的成员、在 H.HiddenExcludeUsers 中、
+//     所在部门（含其上级部门）在 H.HiddenExcludeDepartments 中；
+//     全部通过时查看者可以看到该部门；
+//   - IsHidden 的用户没有白名单，只有本人可见；
+//   - 其它用户只要所在部门有一个可见即可见。
+
+// VisibilityAudit 可见范围审计，基于一次获取的部门树和用户在本地计算
+type VisibilityAudit struct {
+	tree    *OrgTree
+	users   map[string]User   // 按用户 id
+	byEmail map[string]string // 小写邮箱 -> 用户 id
+}
+
+// AuditVisibility 获取 rootID（为空时为$root）下的部门与用户，以及白名单中引用的范围外用户，用于可见范围审计
+func (d *DepartmentService) AuditVisibility(ctx context.Context, rootID string) (*VisibilityAudit, error) {
+	tree, err := d.Tree(ctx, rootID, WithTreeUsers())
+	if err != nil {
+		return nil, err
+	}
+	a := &VisibilityAudit{tree: tree, users: map[string]User{}, byEmail: map[string]string{}}
+	var whitelisted []string
+	tree.Walk(func(n *OrgNode) bool {
+		for _, u := range n.Users {
+			a.addMember(u, n.ID)
+		}
+		whitelisted = append(whitelisted, n.HiddenExcludeUsers...)
+		return true
+	})
+	var missing []string
+	for _, id := range whitelisted {
+		if _, ok := a.users[id]; !ok && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		users, _, err := d.User.ListAllByIds(ctx, missing)
+		if err != nil {
+			return nil, fmt.Errorf("resolve whitelisted users: %w", err)
+		}
+		for _, u := range users {
+			a.add(u)
+		}
+	}
+	return a, nil
+}
+
+func (a *VisibilityAudit) add(u User) {
+	a.users[u.ID] = u
+	a.byEmail[strings.ToLower(u.Email)] = u.ID
+}
+
+// addMember 记录部门 deptID 下的用户，合并其在各部门中的部门ID
+func (a *VisibilityAudit) addMember(u User, deptID string) {
+	u.DepartmentIds = mergeDepartmentIds(a.users[u.ID].DepartmentIds, u, deptID)
+	a.add(u)
+}
+
+// Tree 返回审计使用的部门树
+func (a *VisibilityAudit) Tree() *OrgTree { return a.tree }
+
+// VisibilityRef 白名单或可见结果中引用的对象
+type VisibilityRef struct {
+	ID    string
+	Name  string
+	Email string // 用户邮箱或部门邮件组地址
+	Path  string // 部门名称路径，用户为空
+}
+
+// HiddenObject 一个被隐藏的部门或用户及其白名单
+type HiddenObject struct {
+	Kind                 string // "department" 或 "user"
+	VisibilityRef               // 被隐藏的对象
+	WhitelistUsers       []VisibilityRef
+	WhitelistDepartments []VisibilityRef
+	Unresolved           []string // 白名单中无法解析的用户或部门 id
+}
+
+// HiddenObjects 列出所有 IsHidden 的部门和用户，部门的白名单会解析为姓名、邮箱与部门路径。
+// 隐藏部门的下级部门虽然同样不可见，但不单独列出。
+func (a *VisibilityAudit) HiddenObjects() []HiddenObject {
+	var list []HiddenObject
+	a.tree.Walk(func(n *OrgNode) bool {
+		if !n.IsHidden {
+			return true
+		}
+		obj := HiddenObject{Kind: "department", VisibilityRef: a.deptRef(n)}
+		for _, id := range n.HiddenExcludeUsers {
+			if u, ok := a.users[id]; ok {
+				obj.WhitelistUsers = append(obj.WhitelistUsers, userRef(u))
+			} else {
+				obj.Unresolved = append(obj.Unresolved, id)
+			}
+		}
+		for _, id := range n.HiddenExcludeDepartments {
+			if dn := a.tree.Get(id); dn != nil {
+				obj.WhitelistDepartments = append(obj.WhitelistDepartments, a.deptRef(dn))
+			} else {
+				obj.Unresolved = append(obj.Unresolved, id)
+			}
+		}
+		list = append(list, obj)
+		return true
+	})
+	for _, u := range a.sortedUsers() {
+		if u.IsHidden {
+			list = append(list, HiddenObject{Kind: "user", VisibilityRef: userRef(u)})
+		}
+	}
+	return list
+}
+
+// VisibleItem 查看者可以看到的一个隐藏对象
+type VisibleItem struct {
+	VisibilityRef
+	Reason string // 可见的原因
+}
+
+// UserVisibility 某个用户能看到的隐藏对象
+type UserVisibility struct {
+	Viewer      User
+	Departments []VisibleItem // 能看到的处于隐藏状态的部门
+	Users       []VisibleItem // 能看到的隐藏用户，以及只属于隐藏部门的用户
+}
+
+// VisibleTo 计算 email 对应的用户能看到哪些处于隐藏状态的部门和用户
+func (a *VisibilityAudit) VisibleTo(email string) (*UserVisibility, error) {
+	viewer, ok := a.userByEmail(email)
+	if !ok {
+		return nil, fmt.Errorf("user %s not found in audit scope", email)
+	}
+	member := a.memberOf(viewer)
+	rst := &UserVisibility{Viewer: viewer}
+	a.tree.Walk(func(n *OrgNode) bool {
+		if !a.hidden(n) {
+			return true
+		}
+		if ok, reason := a.canSeeDept(viewer, member, n); ok {
+			rst.Departments = append(rst.Departments, VisibleItem{VisibilityRef: a.deptRef(n), Reason: reason})
+		}
+		return true
+	})
+	for _, u := range a.sortedUsers() {
+		if !a.userHidden(u) {
+			continue
+		}
+		if ok, reason := a.canSeeUser(viewer, member, u); ok {
+			rst.Users = append(rst.Users, VisibleItem{VisibilityRef: userRef(u), Reason: reason})
+		}
+	}
+	return rst, nil
+}
+
+// DepartmentViewers 返回审计范围内能看到该部门的全部用户
+func (a *VisibilityAudit) DepartmentViewers(id string) ([]VisibleItem, error) {
+	n := a.tree.Get(id)
+	if n == nil {
+		return nil, fmt.Errorf("department %s not found in audit scope", id)
+	}
+	var list []VisibleItem
+	for _, v := range a.sortedUsers() {
+		if ok, reason := a.canSeeDept(v, a.memberOf(v), n); ok {
+			list = append(list, VisibleItem{VisibilityRef: userRef(v), Reason: reason})
+		}
+	}
+	return list, nil
+}
+
+// UserViewers 返回审计范围内能看到该用户的全部用户，如用于确认高管邮箱只对指定人员可见
+func (a *VisibilityAudit) UserViewers(email string) ([]VisibleItem, error) {
+	target, ok := a.userByEmail(email)
+	if !ok {
+		return nil, fmt.Errorf("user %s not found in audit scope", email)
+	}
+	var list []VisibleItem
+	for _, v := range a.sortedUsers() {
+		if ok, reason := a.canSeeUser(v, a.memberOf(v), target); ok {
+			list = append(list, VisibleItem{VisibilityRef: userRef(v), Reason: reason})
+		}
+	}
+	return list, nil
+}
+
+func (a *VisibilityAudit) userByEmail(email string) (User, bool) {
+	u, ok := a.users[a.byEmail[strings.ToLower(email)]]
+	return u, ok
+}
+
+// sortedUsers 按邮箱排序返回全部用户，保证输出稳定
+func (a *VisibilityAudit) sortedUsers() []User {
+	list := make([]User, 0, len(a.users))
+	for _, u := range a.users {
+		list = append(list, u)
+	}
+	slices.SortFunc(list, func(x, y User) int { return strings.Compare(x.Email, y.Email) })
+	return list
+}
+
+// memberOf 返回用户所在的部门及其全部上级部门
+func (a *VisibilityAudit) memberOf(u User) map[string]bool {
+	set := map[string]bool{}
+	for _, id := range u.DepartmentIds {
+		for cur := a.tree.Get(id); cur != nil; cur = cur.Parent {
+			set[cur.ID] = true
+		}
+	}
+	return set
+}
+
+// hidden 部门自身或任一上级部门是否隐藏
+func (a *VisibilityAudit) hidden(n *OrgNode) bool {
+	for cur := n; cur != nil; cur = cur.Parent {
+		if cur.IsHidden {
+			return true
+		}
+	}
+	return false
+}
+
+// userHidden 用户是否隐藏，或所在部门全部处于隐藏状态
+func (a *VisibilityAudit) userHidden(u User) bool {
+	if u.IsHidden {
+		return true
+	}
+	inTree := false
+	for _, id := range u.DepartmentIds {
+		if n := a.tree.Get(id); n != nil {
+			inTree = true
+			if !a.hidden(n) {
+				return false
+			}
+		}
+	}
+	return inTree
+}
+
+func (a *VisibilityAudit) canSeeDept(viewer User, member map[string]bool, n *OrgNode) (bool, string) {
+	var reasons []string
+	for cur := n; cur != nil; cur = cur.Parent {
+		if !cur.IsHidden {
+			continue
+		}
+		switch {
+		case member[cur.ID]:
+			reasons = append(reasons, fmt.Sprintf("member of %q", cur.Name))
+		case slices.Contains(cur.HiddenExcludeUsers, viewer.ID):
+			reasons = append(reasons, fmt.Sprintf("whitelisted user of %q", cur.Name))
+		default:
+			i := slices.IndexFunc(cur.HiddenExcludeDepartments, func(id string) bool { return member[id] })
+			if i < 0 {
+				return false, ""
+			}
+			wl := cur.HiddenExcludeDepartments[i]
+			name := wl
+			if wn := a.tree.Get(wl); wn != nil {
+				name = wn.Name
+			}
+			reasons = append(reasons, fmt.Sprintf("in whitelisted department %q of %q", name, cur.Name))
+		}
+	}
+	if len(reasons) == 0 {
+		return true, "not hidden"
+	}
+	slices.Reverse(reasons)
+	return true, strings.Join(reasons, "; ")
+}
+
+func (a *VisibilityAudit) canSeeUser(viewer User, member map[string]bool, u User) (bool, string) {
+	if viewer.ID == u.ID {
+		return true, "self"
+	}
+	if u.IsHidden {
+		return false, ""
+	}
+	inTree := false
+	for _, id := range u.DepartmentIds {
+		n := a.tree.Get(id)
+		if n == nil {
+			continue
+		}
+		inTree = true
+		if ok, reason := a.canSeeDept(viewer, member, n); ok {
+			return true, fmt.Sprintf("via department %q: %s", n.Name, reason)
+		}
+	}
+	if !inTree {
+		return true, "not in audited departments"
+	}
+	return false, ""
+}
+
+func (a *VisibilityAudit) deptRef(n *OrgNode) VisibilityRef {
+	return VisibilityRef{ID: n.ID, Name: n.Name, Email: n.Email, Path: strings.Join(n.Path(), "/")}
+}
+
+func userRef(u User) VisibilityRef {
+	return VisibilityRef{ID: u.ID, Name: u.Name, Email: u.Email}
+}
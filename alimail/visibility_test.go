@@ -0,0 +1,101 @@
+package alimail
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// visibilityDirectory 假服务中的部门树：$root 下有 研发(A)、产品(B) 与隐藏的 高管(H)，
+// u1 同时属于研发与高管，部门成员列表只返回当前部门
+func visibilityDirectory(req recordedRequest) (int, any) {
+	if req.Method != MethodGet {
+		return 0, nil
+	}
+	depts := map[string]Department{
+		RootDepartmentID: {ID: RootDepartmentID, Name: "root"},
+		"A":              {ID: "A", Name: "研发", ParentID: RootDepartmentID},
+		"B":              {ID: "B", Name: "产品", ParentID: RootDepartmentID},
+		"H":              {ID: "H", Name: "高管", ParentID: RootDepartmentID, IsHidden: true},
+	}
+	// 与真实接口一样，u1 的上级只通过 managerInfo 返回
+	u1 := User{ID: "u1", Email: "u1@example.com", Name: "张三"}
+	u1.ManagerInfo.Email = "u2@example.com"
+	inDept := func(u User, id string) User {
+		u.DepartmentIds = []string{id}
+		return u
+	}
+	members := map[string][]User{
+		"A": {inDept(u1, "A")},
+		"B": {{ID: "u2", Email: "u2@example.com", DepartmentIds: []string{"B"}}},
+		"H": {inDept(u1, "H"), {ID: "u3", Email: "u3@example.com", DepartmentIds: []string{"H"}}},
+	}
+	first := req.Query.Get("offset") == "0"
+	parts := strings.Split(strings.TrimPrefix(req.Path, "/v2/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "departments":
+		return http.StatusOK, depts[parts[1]]
+	case len(parts) == 3 && parts[2] == "departments":
+		rsp := ListDepartmentDeptsRsp{Departments: []Department{}}
+		for _, id := range []string{"A", "B", "H"} {
+			if d := depts[id]; d.ParentID == parts[1] {
+				rsp.Total++
+				if first {
+					rsp.Departments = append(rsp.Departments, d)
+				}
+			}
+		}
+		return http.StatusOK, rsp
+	case len(parts) == 3 && parts[2] == "users":
+		rsp := ListDepartmentUsersRsp{Users: []User{}, Total: len(members[parts[1]])}
+		if first {
+			rsp.Users = append(rsp.Users, members[parts[1]]...)
+		}
+		return http.StatusOK, rsp
+	}
+	return 0, nil
+}
+
+func TestAuditVisibilityMultiDepartmentUser(t *testing.T) {
+	c, _ := newFakeServer(t, visibilityDirectory)
+	audit, err := c.Department.AuditVisibility(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// u1 同时在研发中，对其他人可见；只在高管中的 u3 不可见
+	emails := func(items []VisibleItem) []string {
+		var list []string
+		for _, it := range items {
+			list = append(list, it.Email)
+		}
+		return list
+	}
+	viewers, err := audit.UserViewers("u1@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := emails(viewers); !reflect.DeepEqual(got, []string{"u1@example.com", "u2@example.com", "u3@example.com"}) {
+		t.Errorf("u1 viewers = %q", got)
+	}
+	viewers, err = audit.UserViewers("u3@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := emails(viewers); !reflect.DeepEqual(got, []string{"u1@example.com", "u3@example.com"}) {
+		t.Errorf("u3 viewers = %q", got)
+	}
+
+	vis, err := audit.VisibleTo("u1@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vis.Departments) != 1 || vis.Departments[0].ID != "H" || vis.Departments[0].Reason != `member of "高管"` {
+		t.Errorf("u1 visible departments = %+v", vis.Departments)
+	}
+	if !reflect.DeepEqual(vis.Viewer.DepartmentIds, []string{"A", "H"}) {
+		t.Errorf("u1 departmentIds = %q", vis.Viewer.DepartmentIds)
+	}
+}
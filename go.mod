@@ -1,5 +1,17 @@
 module github.com/eryajf/go-alimail
 
-go 1.22
+go 1.23.0
 
 require golang.org/x/time v0.6.0
+
+require (
+	github.com/go-ldap/ldap/v3 v3.4.12
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/Azure/go-ntlmssp v0.0.0-20221128193559-754e69321358 // indirect
+	github.com/go-asn1-ber/asn1-ber v1.5.8-0.20250403174932-29230038a667 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+)
@@ -0,0 +1,58 @@
+package ldapsync
+
+import (
+	"context"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// connSearcher 基于 go-ldap 连接的 Searcher，使用 RFC 2696 分页控制
+type connSearcher struct {
+	conn ldap.Client
+}
+
+// NewConnSearcher 包装一个已完成绑定的 go-ldap 连接，如：
+//
+//	conn, err := ldap.DialURL("ldaps://ldap.example.com")
+//	err = conn.Bind("cn=reader,dc=example,dc=com", password)
+//	connector, err := ldapsync.New(client, ldapsync.NewConnSearcher(conn), cfg)
+func NewConnSearcher(conn ldap.Client) Searcher {
+	return connSearcher{conn: conn}
+}
+
+var ldapScopes = map[Scope]int{
+	ScopeSubtree: ldap.ScopeWholeSubtree,
+	ScopeOne:     ldap.ScopeSingleLevel,
+	ScopeBase:    ldap.ScopeBaseObject,
+}
+
+func (s connSearcher) SearchPage(ctx context.Context, req SearchRequest, cookie []byte) ([]Entry, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	var controls []ldap.Control
+	if req.PageSize > 0 {
+		paging := ldap.NewControlPaging(uint32(req.PageSize))
+		paging.SetCookie(cookie)
+		controls = append(controls, paging)
+	}
+	sr := ldap.NewSearchRequest(req.BaseDN, ldapScopes[req.Scope], ldap.NeverDerefAliases, 0, 0, false,
+		req.Filter, req.Attributes, controls)
+	rst, err := s.conn.Search(sr)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]Entry, 0, len(rst.Entries))
+	for _, e := range rst.Entries {
+		attrs := make(map[string][]string, len(e.Attributes))
+		for _, a := range e.Attributes {
+			attrs[a.Name] = a.Values
+		}
+		entries = append(entries, Entry{DN: e.DN, Attributes: attrs})
+	}
+	var next []byte
+	if paging, ok := ldap.FindControl(rst.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging); ok {
+		next = paging.Cookie
+	}
+	return entries, next, nil
+}
@@ -0,0 +1,430 @@
+// Package ldapsync 从 LDAP（如 OpenLDAP、Active Directory）读取用户和组织单元（OU），
+// 映射为 AliMail 的用户与部门树并同步，支持分页查询和基于 modifyTimestamp/uSNChanged 的增量同步。
+package ldapsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eryajf/go-alimail/alimail"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Scope 查询范围
+type Scope int
+
+const (
+	ScopeSubtree Scope = iota // 起点及其全部下级条目
+	ScopeOne                  // 起点的直接下级条目
+	ScopeBase                 // 只查询起点条目本身
+)
+
+// SearchRequest 一次 LDAP 查询
+type SearchRequest struct {
+	BaseDN     string
+	Scope      Scope
+	Filter     string
+	Attributes []string
+	PageSize   int // 每页条目数，为 0 时不分页
+}
+
+// Entry 查询返回的一个条目
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Values 返回属性的全部值，属性名不区分大小写
+func (e Entry) Values(attr string) []string {
+	if v, ok := e.Attributes[attr]; ok {
+		return v
+	}
+	for name, v := range e.Attributes {
+		if strings.EqualFold(name, attr) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Get 返回属性的第一个值
+func (e Entry) Get(attr string) string {
+	if v := e.Values(attr); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Searcher 按页执行 LDAP 查询。cookie 为空表示查询第一页，返回的 next 为空表示没有更多数据。
+// 生产环境使用 NewConnSearcher 包装 go-ldap 连接，测试时可以用内存实现替代。
+type Searcher interface {
+	SearchPage(ctx context.Context, req SearchRequest, cookie []byte) (entries []Entry, next []byte, err error)
+}
+
+// AttributeMap LDAP 属性与 AliMail 用户字段的对应关系，为空时使用默认值，设置为 "-" 表示不映射
+type AttributeMap struct {
+	Email        string // 邮箱，默认 mail
+	Name         string // 姓名，默认 cn
+	Nickname     string // 昵称，默认不映射
+	EmployeeNo   string // 员工编号，默认 employeeNumber
+	JobTitle     string // 职位，默认 title
+	Phone        string // 手机号，默认 mobile
+	WorkPhone    string // 工作电话，默认 telephoneNumber
+	WorkLocation string // 工作地点，默认 l
+	Manager      string // 上级，值为上级条目的 DN，默认 manager
+	OUName       string // OU 对应的部门名称，默认 ou
+}
+
+// Config 连接器配置
+type Config struct {
+	BaseDN     string // 必填，如 "dc=example,dc=com"，部门路径从该节点以下的 OU 开始
+	UserBaseDN string // 用户查询起点，默认 BaseDN
+	UserFilter string // 用户过滤条件，默认 "(&(objectClass=inetOrgPerson)(mail=*))"
+	OUFilter   string // OU 过滤条件，默认 "(objectClass=organizationalUnit)"
+	PageSize   int    // 分页大小，默认 500
+	Attributes AttributeMap
+	// DepartmentRoot OU 树在 AliMail 中挂载的部门路径，如 "LDAP"，为空时挂在根部门下
+	DepartmentRoot string
+	// ChangeAttribute 增量同步使用的属性：OpenLDAP 为 modifyTimestamp（默认），AD 为 uSNChanged
+	ChangeAttribute string
+}
+
+func (c Config) withDefaults() (Config, error) {
+	if c.BaseDN == "" {
+		return c, errors.New("ldapsync: BaseDN is required")
+	}
+	def := func(v *string, d string) {
+		if *v == "" {
+			*v = d
+		}
+	}
+	def(&c.UserBaseDN, c.BaseDN)
+	def(&c.UserFilter, "(&(objectClass=inetOrgPerson)(mail=*))")
+	def(&c.OUFilter, "(objectClass=organizationalUnit)")
+	def(&c.ChangeAttribute, "modifyTimestamp")
+	if c.PageSize <= 0 {
+		c.PageSize = 500
+	}
+	a := &c.Attributes
+	def(&a.Email, "mail")
+	def(&a.Name, "cn")
+	def(&a.EmployeeNo, "employeeNumber")
+	def(&a.JobTitle, "title")
+	def(&a.Phone, "mobile")
+	def(&a.WorkPhone, "telephoneNumber")
+	def(&a.WorkLocation, "l")
+	def(&a.Manager, "manager")
+	def(&a.OUName, "ou")
+	return c, nil
+}
+
+// userAttributes 查询用户时需要返回的属性
+func (c Config) userAttributes() []string {
+	a := c.Attributes
+	var attrs []string
+	for _, name := range []string{a.Email, a.Name, a.Nickname, a.EmployeeNo, a.JobTitle, a.Phone, a.WorkPhone, a.WorkLocation, a.Manager, c.ChangeAttribute} {
+		if name != "" && name != "-" && !slices.Contains(attrs, name) {
+			attrs = append(attrs, name)
+		}
+	}
+	return attrs
+}
+
+// Department 一个 OU 及其对应的 AliMail 部门路径
+type Department struct {
+	DN     string
+	Name   string
+	Path   string // 部门名称路径，含 DepartmentRoot
+	Change string // ChangeAttribute 的值
+}
+
+// User 一个 LDAP 用户映射后的信息
+type User struct {
+	DN           string
+	Email        string
+	Name         string
+	Nickname     string
+	EmployeeNo   string
+	JobTitle     string
+	Phone        string
+	WorkPhone    string
+	WorkLocation string
+	ManagerDN    string
+	ManagerEmail string // 由 ManagerDN 解析得到
+	Department   string // 部门名称路径，含 DepartmentRoot
+	Change       string // ChangeAttribute 的值
+}
+
+// Connector 从 LDAP 读取用户与 OU，并同步到 AliMail
+type Connector struct {
+	client   *alimail.Client
+	searcher Searcher
+	cfg      Config
+	base     *ldap.DN
+}
+
+// New 创建连接器，client 为 nil 时只能读取 LDAP，不能调用 Sync
+func New(client *alimail.Client, searcher Searcher, cfg Config) (*Connector, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	base, err := ldap.ParseDN(cfg.BaseDN)
+	if err != nil {
+		return nil, fmt.Errorf("ldapsync: parse base dn %q: %w", cfg.BaseDN, err)
+	}
+	return &Connector{client: client, searcher: searcher, cfg: cfg, base: base}, nil
+}
+
+// search 执行分页查询，依次读取全部页
+func (c *Connector) search(ctx context.Context, req SearchRequest) ([]Entry, error) {
+	var (
+		all    []Entry
+		cookie []byte
+	)
+	for {
+		entries, next, err := c.searcher.SearchPage(ctx, req, cookie)
+		if err != nil {
+			return nil, fmt.Errorf("ldap search %s %s: %w", req.BaseDN, req.Filter, err)
+		}
+		all = append(all, entries...)
+		if len(next) == 0 {
+			return all, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cookie = next
+	}
+}
+
+// changedSince 在过滤条件上追加增量条件
+func (c *Connector) changedSince(filter, since string) string {
+	if since == "" {
+		return filter
+	}
+	return fmt.Sprintf("(&%s(%s>=%s))", filter, c.cfg.ChangeAttribute, ldap.EscapeFilter(since))
+}
+
+// ReadDepartments 读取 BaseDN 下的全部 OU，按路径排序，上级部门总在下级之前
+func (c *Connector) ReadDepartments(ctx context.Context) ([]Department, error) {
+	attrs := []string{c.cfg.Attributes.OUName, c.cfg.ChangeAttribute}
+	entries, err := c.search(ctx, SearchRequest{
+		BaseDN:     c.cfg.BaseDN,
+		Filter:     c.cfg.OUFilter,
+		Attributes: attrs,
+		PageSize:   c.cfg.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if name := e.Get(c.cfg.Attributes.OUName); name != "" {
+			names[dnKey(e.DN)] = name
+		}
+	}
+	var list []Department
+	for _, e := range entries {
+		dn, err := ldap.ParseDN(e.DN)
+		if err != nil {
+			return nil, fmt.Errorf("parse dn %q: %w", e.DN, err)
+		}
+		segments, ok := c.ouPath(dn, names)
+		if !ok || len(segments) == 0 {
+			continue
+		}
+		list = append(list, Department{
+			DN:     e.DN,
+			Name:   segments[len(segments)-1],
+			Path:   c.departmentPath(segments),
+			Change: e.Get(c.cfg.ChangeAttribute),
+		})
+	}
+	slices.SortFunc(list, func(a, b Department) int { return strings.Compare(a.Path, b.Path) })
+	return list, nil
+}
+
+// ReadUsers 读取用户，since 不为空时只读取 ChangeAttribute 不早于 since 的用户。
+// depts 通常来自 ReadDepartments，用于确定部门名称，为 nil 时使用 DN 中的 ou 值。
+// 返回的 cursor 为读取到的最大变更值，没有用户时等于 since。
+func (c *Connector) ReadUsers(ctx context.Context, since string, depts []Department) (users []User, cursor string, err error) {
+	names := make(map[string]string, len(depts))
+	for _, d := range depts {
+		names[dnKey(d.DN)] = d.Name
+	}
+	entries, err := c.search(ctx, SearchRequest{
+		BaseDN:     c.cfg.UserBaseDN,
+		Filter:     c.changedSince(c.cfg.UserFilter, since),
+		Attributes: c.cfg.userAttributes(),
+		PageSize:   c.cfg.PageSize,
+	})
+	if err != nil {
+		return nil, since, err
+	}
+	cursor = since
+	emails := make(map[string]string, len(entries))
+	for _, e := range entries {
+		u, err := c.mapUser(e, names)
+		if err != nil {
+			return nil, since, err
+		}
+		if u.Email == "" {
+			continue
+		}
+		if laterChange(u.Change, cursor) {
+			cursor = u.Change
+		}
+		emails[dnKey(u.DN)] = u.Email
+		users = append(users, u)
+	}
+	// 上级不在本次结果中时（增量同步或上级在查询范围外）单独查询其邮箱
+	for i := range users {
+		dn := users[i].ManagerDN
+		if dn == "" {
+			continue
+		}
+		email, ok := emails[dnKey(dn)]
+		if !ok {
+			if email, err = c.lookupEmail(ctx, dn); err != nil {
+				return nil, since, err
+			}
+			emails[dnKey(dn)] = email
+		}
+		users[i].ManagerEmail = email
+	}
+	return users, cursor, nil
+}
+
+// lookupEmail 查询指定 DN 的邮箱，条目不存在时返回空字符串
+func (c *Connector) lookupEmail(ctx context.Context, dn string) (string, error) {
+	entries, _, err := c.searcher.SearchPage(ctx, SearchRequest{
+		BaseDN:     dn,
+		Scope:      ScopeBase,
+		Filter:     "(objectClass=*)",
+		Attributes: []string{c.cfg.Attributes.Email},
+	}, nil)
+	if err != nil {
+		var lerr *ldap.Error
+		if errors.As(err, &lerr) && lerr.ResultCode == ldap.LDAPResultNoSuchObject {
+			return "", nil
+		}
+		return "", fmt.Errorf("lookup manager %s: %w", dn, err)
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(entries[0].Get(c.cfg.Attributes.Email)), nil
+}
+
+func (c *Connector) mapUser(e Entry, names map[string]string) (User, error) {
+	a := c.cfg.Attributes
+	get := func(attr string) string {
+		if attr == "" || attr == "-" {
+			return ""
+		}
+		return strings.TrimSpace(e.Get(attr))
+	}
+	u := User{
+		DN:           e.DN,
+		Email:        get(a.Email),
+		Name:         get(a.Name),
+		Nickname:     get(a.Nickname),
+		EmployeeNo:   get(a.EmployeeNo),
+		JobTitle:     get(a.JobTitle),
+		Phone:        get(a.Phone),
+		WorkPhone:    get(a.WorkPhone),
+		WorkLocation: get(a.WorkLocation),
+		ManagerDN:    get(a.Manager),
+		Change:       get(c.cfg.ChangeAttribute),
+	}
+	if u.Name == "" {
+		u.Name = u.Email
+	}
+	dn, err := ldap.ParseDN(e.DN)
+	if err != nil {
+		return u, fmt.Errorf("parse dn %q: %w", e.DN, err)
+	}
+	if len(dn.RDNs) > 0 {
+		dn = &ldap.DN{RDNs: dn.RDNs[1:]}
+	}
+	segments, ok := c.ouPath(dn, names)
+	if !ok {
+		return u, fmt.Errorf("user %q is outside base dn %q", e.DN, c.cfg.BaseDN)
+	}
+	u.Department = c.departmentPath(segments)
+	return u, nil
+}
+
+// ouPath 返回 dn 相对 BaseDN 的 OU 名称路径，从最上级开始；
+// 非 ou 的 RDN（如 AD 的 cn=Users 容器）会被跳过
+func (c *Connector) ouPath(dn *ldap.DN, names map[string]string) ([]string, bool) {
+	n := len(dn.RDNs) - len(c.base.RDNs)
+	if n < 0 || !c.base.EqualFold(&ldap.DN{RDNs: dn.RDNs[n:]}) {
+		return nil, false
+	}
+	var segments []string
+	for i := n - 1; i >= 0; i-- {
+		rdn := dn.RDNs[i]
+		if len(rdn.Attributes) != 1 || !strings.EqualFold(rdn.Attributes[0].Type, "ou") {
+			continue
+		}
+		name := rdn.Attributes[0].Value
+		if v, ok := names[dnKey((&ldap.DN{RDNs: dn.RDNs[i:]}).String())]; ok {
+			name = v
+		}
+		segments = append(segments, name)
+	}
+	return segments, true
+}
+
+func (c *Connector) departmentPath(segments []string) string {
+	return strings.Join(append(alimail.SplitDepartmentPath(c.cfg.DepartmentRoot), segments...), "/")
+}
+
+// dnKey 返回用于比较的 DN，大小写与空格差异不影响结果
+func dnKey(dn string) string {
+	if parsed, err := ldap.ParseDN(dn); err == nil {
+		dn = parsed.String()
+	}
+	return strings.ToLower(dn)
+}
+
+// changeTimeLayouts LDAP GeneralizedTime 的常见格式
+var changeTimeLayouts = []string{"20060102150405Z0700", "20060102150405.999999999Z0700"}
+
+// laterChange 判断变更值 a 是否晚于 b：数字（uSNChanged）按数值比较，
+// GeneralizedTime（modifyTimestamp）按时间比较，其它按字符串比较
+func laterChange(a, b string) bool {
+	if a == "" {
+		return false
+	}
+	if b == "" {
+		return true
+	}
+	if x, err := strconv.ParseInt(a, 10, 64); err == nil {
+		if y, err := strconv.ParseInt(b, 10, 64); err == nil {
+			return x > y
+		}
+	}
+	if x, ok := parseChangeTime(a); ok {
+		if y, ok := parseChangeTime(b); ok {
+			return x.After(y)
+		}
+	}
+	return a > b
+}
+
+func parseChangeTime(s string) (time.Time, bool) {
+	for _, layout := range changeTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,367 @@
+package ldapsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eryajf/go-alimail/alimail"
+)
+
+// memDirectory 内存中的 LDAP 目录，支持分页和简单过滤条件：&、|、!、=、>=、存在性（attr=*）
+type memDirectory struct {
+	entries []Entry
+	pages   int
+}
+
+func (d *memDirectory) add(dn string, attrs map[string][]string) {
+	d.entries = append(d.entries, Entry{DN: dn, Attributes: attrs})
+}
+
+func (d *memDirectory) SearchPage(ctx context.Context, req SearchRequest, cookie []byte) ([]Entry, []byte, error) {
+	d.pages++
+	var matched []Entry
+	for _, e := range d.entries {
+		if !inScope(e.DN, req.BaseDN, req.Scope) {
+			continue
+		}
+		ok, err := matchFilter(req.Filter, e)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			matched = append(matched, e)
+		}
+	}
+	start := 0
+	if len(cookie) > 0 {
+		start, _ = strconv.Atoi(string(cookie))
+	}
+	if req.PageSize <= 0 || start+req.PageSize >= len(matched) {
+		return matched[start:], nil, nil
+	}
+	end := start + req.PageSize
+	return matched[start:end], []byte(strconv.Itoa(end)), nil
+}
+
+func inScope(dn, base string, scope Scope) bool {
+	dn, base = dnKey(dn), dnKey(base)
+	switch scope {
+	case ScopeBase:
+		return dn == base
+	case ScopeOne:
+		_, parent, _ := strings.Cut(dn, ",")
+		return parent == base
+	}
+	return dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+func matchFilter(filter string, e Entry) (bool, error) {
+	ok, rest, err := evalFilter(filter, e)
+	if err == nil && rest != "" {
+		err = fmt.Errorf("trailing filter %q", rest)
+	}
+	return ok, err
+}
+
+func evalFilter(f string, e Entry) (bool, string, error) {
+	if !strings.HasPrefix(f, "(") {
+		return false, "", fmt.Errorf("bad filter %q", f)
+	}
+	f = f[1:]
+	switch f[0] {
+	case '&', '|', '!':
+		op := f[0]
+		f = f[1:]
+		var results []bool
+		for strings.HasPrefix(f, "(") {
+			ok, rest, err := evalFilter(f, e)
+			if err != nil {
+				return false, "", err
+			}
+			results = append(results, ok)
+			f = rest
+		}
+		rest := strings.TrimPrefix(f, ")")
+		switch op {
+		case '&':
+			return !slices.Contains(results, false), rest, nil
+		case '|':
+			return slices.Contains(results, true), rest, nil
+		}
+		return !results[0], rest, nil
+	}
+	end := strings.IndexByte(f, ')')
+	item, rest := f[:end], f[end+1:]
+	if attr, value, ok := strings.Cut(item, ">="); ok {
+		for _, v := range e.Values(attr) {
+			if v == value || laterChange(v, value) {
+				return true, rest, nil
+			}
+		}
+		return false, rest, nil
+	}
+	attr, value, _ := strings.Cut(item, "=")
+	values := e.Values(attr)
+	if value == "*" {
+		return len(values) > 0, rest, nil
+	}
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, value) }), rest, nil
+}
+
+func newDirectory() *memDirectory {
+	d := &memDirectory{}
+	ou := func(dn, name, ts string) {
+		d.add(dn, map[string][]string{"objectClass": {"organizationalUnit"}, "ou": {name}, "modifyTimestamp": {ts}})
+	}
+	person := func(dn, mail, cn, title, manager, ts string) {
+		attrs := map[string][]string{
+			"objectClass":     {"inetOrgPerson"},
+			"mail":            {mail},
+			"cn":              {cn},
+			"title":           {title},
+			"modifyTimestamp": {ts},
+		}
+		if manager != "" {
+			attrs["manager"] = []string{manager}
+		}
+		d.add(dn, attrs)
+	}
+	ou("ou=Engineering,dc=example,dc=com", "研发中心", "20240101000000Z")
+	ou("ou=Platform,ou=Engineering,dc=example,dc=com", "平台部", "20240101000000Z")
+	ou("ou=Sales,dc=example,dc=com", "Sales", "20240101000000Z")
+	person("uid=alice,ou=Engineering,dc=example,dc=com", "alice@example.com", "Alice", "CTO", "", "20240102000000Z")
+	person("uid=bob,ou=Platform,ou=Engineering,dc=example,dc=com", "bob@example.com", "Bob", "SRE",
+		"uid=alice,ou=Engineering,dc=example,dc=com", "20240103000000Z")
+	person("uid=carol,ou=Platform,ou=Engineering,dc=example,dc=com", "carol@example.com", "Carol", "SRE",
+		"uid=bob,ou=Platform,ou=Engineering,dc=example,dc=com", "20240104000000Z")
+	person("uid=dave,ou=Sales,dc=example,dc=com", "dave@example.com", "Dave", "Account Manager", "", "20240105000000Z")
+	// 没有邮箱的条目不参与同步
+	d.add("uid=svc,dc=example,dc=com", map[string][]string{"objectClass": {"inetOrgPerson"}, "cn": {"svc"}})
+	return d
+}
+
+func TestReadUsersPagedWithDepartments(t *testing.T) {
+	dir := newDirectory()
+	c, err := New(nil, dir, Config{BaseDN: "dc=example,dc=com", PageSize: 2, DepartmentRoot: "LDAP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	depts, err := c.ReadDepartments(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, d := range depts {
+		paths = append(paths, d.Path)
+	}
+	if want := []string{"LDAP/Sales", "LDAP/研发中心", "LDAP/研发中心/平台部"}; !slices.Equal(paths, want) {
+		t.Errorf("department paths = %q, want %q", paths, want)
+	}
+
+	dir.pages = 0
+	users, cursor, err := c.ReadUsers(context.Background(), "", depts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.pages != 2 {
+		t.Errorf("read %d pages, want 2", dir.pages)
+	}
+	if len(users) != 4 {
+		t.Fatalf("got %d users, want 4", len(users))
+	}
+	if cursor != "20240105000000Z" {
+		t.Errorf("cursor = %q", cursor)
+	}
+	bob := users[slices.IndexFunc(users, func(u User) bool { return u.Email == "bob@example.com" })]
+	if bob.Department != "LDAP/研发中心/平台部" || bob.ManagerEmail != "alice@example.com" || bob.JobTitle != "SRE" {
+		t.Errorf("bob = %+v", bob)
+	}
+
+	// 增量读取：只返回变更值不早于 cursor 的用户，上级不在结果中时单独查询
+	users, _, err = c.ReadUsers(context.Background(), "20240104000000Z", depts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Email != "carol@example.com" || users[0].ManagerEmail != "bob@example.com" {
+		t.Errorf("incremental users = %+v", users)
+	}
+}
+
+// fakeAliMail 保存部门和用户状态的假服务
+type fakeAliMail struct {
+	mu    sync.Mutex
+	depts map[string]alimail.Department
+	users map[string]alimail.User
+	calls []string
+}
+
+func newFakeAliMail(t *testing.T) (*alimail.Client, *fakeAliMail) {
+	t.Helper()
+	f := &fakeAliMail{depts: map[string]alimail.Department{}, users: map[string]alimail.User{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	c := alimail.NewClient("id", "secret")
+	c.SetBaseURL(srv.URL)
+	return c, f
+}
+
+func (f *fakeAliMail) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.HasSuffix(r.URL.Path, "/token") {
+		json.NewEncoder(w).Encode(alimail.TokenResponse{TokenType: "bearer", AccessToken: "t", ExpiresIn: 3600})
+		return
+	}
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[1] == "departments" && parts[3] == "departments":
+		var children []alimail.Department
+		for _, d := range f.depts {
+			if d.ParentID == parts[2] {
+				children = append(children, d)
+			}
+		}
+		json.NewEncoder(w).Encode(alimail.ListDepartmentDeptsRsp{Departments: children, Total: len(children)})
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/departments":
+		var d alimail.Department
+		json.NewDecoder(r.Body).Decode(&d)
+		d.ID = fmt.Sprintf("d%d", len(f.depts)+1)
+		f.depts[d.ID] = d
+		json.NewEncoder(w).Encode(d)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/users":
+		var list []alimail.User
+		if r.URL.Query().Get("offset") == "0" {
+			for _, u := range f.users {
+				list = append(list, u)
+			}
+		}
+		json.NewEncoder(w).Encode(alimail.ListUsersRsp{Users: list, Total: len(f.users)})
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/users":
+		var u alimail.User
+		json.NewDecoder(r.Body).Decode(&u)
+		f.users[u.Email] = u
+		json.NewEncoder(w).Encode(u)
+	case len(parts) == 3 && parts[1] == "users":
+		u, ok := f.users[parts[2]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"detailErrorCode":"UserNotFound","message":"user not found"}`)
+			return
+		}
+		if r.Method == http.MethodPatch {
+			var req alimail.UpdateUserReq
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.JobTitle != "" {
+				u.JobTitle = req.JobTitle
+			}
+			if req.ManagerEmail != "" {
+				u.ManagerEmail = req.ManagerEmail
+			}
+			if req.DepartmentIds != nil {
+				u.DepartmentIds = req.DepartmentIds
+			}
+			f.users[u.Email] = u
+		}
+		json.NewEncoder(w).Encode(u)
+	default:
+		http.Error(w, `{"message":"unexpected request"}`, http.StatusBadRequest)
+	}
+}
+
+func (f *fakeAliMail) takeCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = nil
+	return calls
+}
+
+func TestSyncFullThenIncremental(t *testing.T) {
+	ctx := context.Background()
+	dir := newDirectory()
+	client, fake := newFakeAliMail(t)
+	c, err := New(client, dir, Config{BaseDN: "dc=example,dc=com", PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := c.Sync(ctx, SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.Created) != 4 || len(dry.Departments) != 3 || len(fake.depts) != 0 || len(fake.users) != 0 {
+		t.Fatalf("dry run = %+v, depts %d, users %d", dry, len(fake.depts), len(fake.users))
+	}
+
+	rst, err := c.Sync(ctx, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rst.Failures) > 0 || len(rst.Created) != 4 || rst.Cursor != "20240105000000Z" {
+		t.Fatalf("full sync = %+v", rst)
+	}
+	bob := fake.users["bob@example.com"]
+	if d := fake.depts[bob.DepartmentIds[0]]; d.Name != "平台部" || fake.depts[d.ParentID].Name != "研发中心" {
+		t.Errorf("bob is in %+v", d)
+	}
+	if bob.ManagerEmail != "alice@example.com" || fake.users["carol@example.com"].ManagerEmail != "bob@example.com" {
+		t.Errorf("managers not set: bob %q carol %q", bob.ManagerEmail, fake.users["carol@example.com"].ManagerEmail)
+	}
+
+	// Dave 调到平台部并改了职位，增量同步只查询和更新变化的用户
+	dir.entries = slices.DeleteFunc(dir.entries, func(e Entry) bool { return strings.HasPrefix(e.DN, "uid=dave,") })
+	dir.add("uid=dave,ou=Platform,ou=Engineering,dc=example,dc=com", map[string][]string{
+		"objectClass":     {"inetOrgPerson"},
+		"mail":            {"dave@example.com"},
+		"cn":              {"Dave"},
+		"title":           {"SRE"},
+		"modifyTimestamp": {"20240201000000Z"},
+	})
+	fake.takeCalls()
+
+	inc, err := c.Sync(ctx, SyncOptions{Since: rst.Cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(inc.Updated, []string{"dave@example.com"}) || len(inc.Created) != 0 || inc.Cursor != "20240201000000Z" {
+		t.Errorf("incremental sync = %+v", inc)
+	}
+	for _, call := range fake.takeCalls() {
+		if strings.HasPrefix(call, "GET /v2/users/") && call != "GET /v2/users/dave@example.com" {
+			t.Errorf("unexpected lookup %s", call)
+		}
+	}
+	dave := fake.users["dave@example.com"]
+	if dave.JobTitle != "SRE" || dave.DepartmentIds[0] != bob.DepartmentIds[0] {
+		t.Errorf("dave = %+v", dave)
+	}
+}
+
+func TestLaterChange(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1000", "999", true},
+		{"999", "1000", false},
+		{"20240102000000Z", "20240101235959Z", true},
+		{"20240101000000.5Z", "20240101000000Z", true},
+		{"20240101080000+0800", "20240101000001Z", false},
+		{"", "1", false},
+		{"1", "", true},
+	}
+	for _, tt := range tests {
+		if got := laterChange(tt.a, tt.b); got != tt.want {
+			t.Errorf("laterChange(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package ldapsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eryajf/go-alimail/alimail"
+)
+
+// CreateReq 映射为创建用户请求，departmentID 为 Department 对应的部门ID，
+// 新帐号首次登录时需要修改密码
+func (u User) CreateReq(password, departmentID string) alimail.CreateUserReq {
+	return alimail.CreateUserReq{
+		Email:                         u.Email,
+		Password:                      password,
+		Name:                          u.Name,
+		Nickname:                      u.Nickname,
+		EmployeeNo:                    u.EmployeeNo,
+		JobTitle:                      u.JobTitle,
+		WorkLocation:                  u.WorkLocation,
+		DepartmentIds:                 []string{departmentID},
+		Phone:                         u.Phone,
+		WorkPhone:                     u.WorkPhone,
+		ManagerEmail:                  u.ManagerEmail,
+		ForceChangePasswordNextSignIn: true,
+	}
+}
+
+// UpdateReq 与 AliMail 中的用户对比，映射为只包含变化字段的更新请求，没有变化时返回 false。
+// departmentID 为空表示部门尚未创建（DryRun），此时部门视为变化但不写入请求。
+// LDAP 中为空的属性不会写入请求，因此不会清空 AliMail 中的字段。
+func (u User) UpdateReq(current alimail.User, departmentID string) (alimail.PatchUserReq, bool) {
+	req := alimail.PatchUserReq{BaseUserReq: alimail.BaseUserReq{Email: current.Email}}
+	changed := false
+	str := func(old, new string, dst **string) {
+		if new != "" && new != old {
+			*dst = alimail.Ptr(new)
+			changed = true
+		}
+	}
+	str(current.Name, u.Name, &req.Name)
+	str(current.Nickname, u.Nickname, &req.Nickname)
+	str(current.EmployeeNo, u.EmployeeNo, &req.EmployeeNo)
+	str(current.JobTitle, u.JobTitle, &req.JobTitle)
+	str(current.WorkLocation, u.WorkLocation, &req.WorkLocation)
+	str(current.Phone, u.Phone, &req.Phone)
+	str(current.WorkPhone, u.WorkPhone, &req.WorkPhone)
+	manager := current.ManagerEmail
+	if manager == "" {
+		manager = current.ManagerInfo.Email
+	}
+	if u.ManagerEmail != "" && !strings.EqualFold(u.ManagerEmail, manager) {
+		req.ManagerEmail = alimail.Ptr(u.ManagerEmail)
+		changed = true
+	}
+	if departmentID == "" || len(current.DepartmentIds) != 1 || current.DepartmentIds[0] != departmentID {
+		if departmentID != "" {
+			req.DepartmentIds = &[]string{departmentID}
+		}
+		changed = true
+	}
+	return req, changed
+}
+
+// SyncOptions 同步选项
+type SyncOptions struct {
+	// Since 上次同步返回的 SyncResult.Cursor，为空时全量同步
+	Since  string
+	DryRun bool // 只计算变更，不创建部门，也不创建或修改用户
+}
+
+// Failure 同步失败的用户
+type Failure struct {
+	Email string
+	Err   error
+}
+
+// SyncResult 同步结果
+type SyncResult struct {
+	// Cursor 下次增量同步时作为 SyncOptions.Since 传入；有用户同步失败时保持为本次的 Since，下次会重试
+	Cursor      string
+	Departments []string // 新建（DryRun 时为将要新建）的部门路径
+	Created     []string // 新建的用户邮箱
+	Updated     []string // 更新的用户邮箱
+	Unchanged   int
+	Failures    []Failure
+}
+
+// Sync 将 LDAP 中的 OU 和用户同步到 AliMail：OU 按路径创建为部门，不存在的用户创建（随机初始密码），
+// 信息或部门变化的用户更新，每个用户只属于其所在 OU 对应的部门。
+// Since 不为空时只处理此后变化的 OU 和用户。LDAP 中删除的条目无法通过增量查询发现，同步不会删除或冻结 AliMail 帐号。
+// 部门创建失败时中止同步；单个用户失败不影响其它用户，记录在 Failures 中。
+func (c *Connector) Sync(ctx context.Context, opts SyncOptions) (*SyncResult, error) {
+	if c.client == nil {
+		return nil, errors.New("ldapsync: Sync requires an alimail client")
+	}
+	depts, err := c.ReadDepartments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, cursor, err := c.ReadUsers(ctx, opts.Since, depts)
+	if err != nil {
+		return nil, err
+	}
+	rst := &SyncResult{Cursor: cursor}
+	ids := map[string]string{}
+	for _, d := range depts {
+		if opts.Since != "" && d.Change != opts.Since && !laterChange(d.Change, opts.Since) {
+			continue
+		}
+		if laterChange(d.Change, rst.Cursor) {
+			rst.Cursor = d.Change
+		}
+		if _, err := c.departmentID(ctx, d.Path, ids, rst, opts.DryRun); err != nil {
+			return rst, err
+		}
+	}
+
+	// 全量同步时一次列出全部帐号，增量同步时逐个查询
+	var live map[string]alimail.User
+	if opts.Since == "" {
+		all, err := c.client.User.All(ctx, alimail.ListUsersReq{})
+		if err != nil {
+			return rst, fmt.Errorf("list users: %w", err)
+		}
+		live = make(map[string]alimail.User, len(all))
+		for _, u := range all {
+			live[strings.ToLower(u.Email)] = u
+		}
+	}
+	fail := func(email string, err error) {
+		rst.Failures = append(rst.Failures, Failure{Email: email, Err: err})
+	}
+	// 先创建全部新用户，再更新已有用户并设置新用户的上级，避免上级尚未创建
+	var updates []alimail.PatchUserReq
+	managers := map[string]string{}
+	for _, u := range users {
+		if err := ctx.Err(); err != nil {
+			return rst, err
+		}
+		current, exists, err := c.currentUser(ctx, live, u.Email)
+		if err != nil {
+			fail(u.Email, err)
+			continue
+		}
+		deptID, err := c.departmentID(ctx, u.Department, ids, rst, opts.DryRun)
+		if err != nil {
+			fail(u.Email, err)
+			continue
+		}
+		if exists {
+			if req, changed := u.UpdateReq(current, deptID); changed {
+				updates = append(updates, req)
+			} else {
+				rst.Unchanged++
+			}
+			continue
+		}
+		if !opts.DryRun {
+			if err := c.createUser(ctx, u, deptID); err != nil {
+				fail(u.Email, err)
+				continue
+			}
+		}
+		rst.Created = append(rst.Created, u.Email)
+		if u.ManagerEmail != "" {
+			managers[u.Email] = u.ManagerEmail
+		}
+	}
+	for _, req := range updates {
+		if !opts.DryRun {
+			if _, err := c.client.User.Patch(ctx, req); err != nil {
+				fail(req.Email, err)
+				continue
+			}
+		}
+		rst.Updated = append(rst.Updated, req.Email)
+	}
+	if !opts.DryRun {
+		for _, email := range rst.Created {
+			manager, ok := managers[email]
+			if !ok {
+				continue
+			}
+			req := alimail.PatchUserReq{BaseUserReq: alimail.BaseUserReq{Email: email}, ManagerEmail: &manager}
+			if _, err := c.client.User.Patch(ctx, req); err != nil {
+				fail(email, fmt.Errorf("set manager: %w", err))
+			}
+		}
+	}
+	if len(rst.Failures) > 0 {
+		rst.Cursor = opts.Since
+	}
+	return rst, nil
+}
+
+// currentUser 返回 AliMail 中的帐号，live 为 nil 时按邮箱查询
+func (c *Connector) currentUser(ctx context.Context, live map[string]alimail.User, email string) (alimail.User, bool, error) {
+	if live != nil {
+		u, ok := live[strings.ToLower(email)]
+		return u, ok, nil
+	}
+	u, err := c.client.User.Get(ctx, alimail.BaseUserReq{Email: email})
+	if err != nil {
+		var apiErr *alimail.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return alimail.User{}, false, nil
+		}
+		return alimail.User{}, false, err
+	}
+	return *u, true, nil
+}
+
+// departmentID 返回部门路径对应的部门ID，不存在时创建；DryRun 时不创建并返回空字符串
+func (c *Connector) departmentID(ctx context.Context, path string, ids map[string]string, rst *SyncResult, dryRun bool) (string, error) {
+	if id, ok := ids[path]; ok {
+		return id, nil
+	}
+	id, err := c.client.Department.ResolvePath(ctx, path)
+	if errors.Is(err, alimail.ErrDepartmentNotFound) {
+		rst.Departments = append(rst.Departments, path)
+		id, err = "", nil
+		if !dryRun {
+			id, err = c.client.Department.EnsurePath(ctx, path)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	ids[path] = id
+	return id, nil
+}
+
+func (c *Connector) createUser(ctx context.Context, u User, deptID string) error {
+	password, err := c.client.PasswordPolicy().Generate(alimail.PasswordContext{Name: u.Name, Email: u.Email})
+	if err != nil {
+		return err
+	}
+	req := u.CreateReq(password, deptID)
+	req.ManagerEmail = ""
+	_, err = c.client.User.Create(ctx, req)
+	return err
+}
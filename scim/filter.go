@@ -0,0 +1,391 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SCIM 过滤表达式（RFC 7644 3.4.2.2），在资源的 JSON 表示上求值：
+//
+//	userName eq "a@example.com"
+//	emails[type eq "work" and value co "@example.com"]
+//	active eq true and not (title pr)
+
+// filter 已解析的过滤表达式
+type filter interface {
+	match(res map[string]any) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right filter
+}
+
+func (f logicalFilter) match(res map[string]any) bool {
+	if f.and {
+		return f.left.match(res) && f.right.match(res)
+	}
+	return f.left.match(res) || f.right.match(res)
+}
+
+type notFilter struct{ inner filter }
+
+func (f notFilter) match(res map[string]any) bool { return !f.inner.match(res) }
+
+// valuePathFilter attr[filter]，多值属性中任一元素满足 inner 即匹配
+type valuePathFilter struct {
+	path  attrPath
+	inner filter
+}
+
+func (f valuePathFilter) match(res map[string]any) bool {
+	for _, v := range f.path.values(res) {
+		if m, ok := v.(map[string]any); ok && f.inner.match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+type compareFilter struct {
+	path  attrPath
+	op    string // eq ne co sw ew gt ge lt le pr
+	value any
+}
+
+func (f compareFilter) match(res map[string]any) bool {
+	values := f.path.values(res)
+	if f.op == "pr" {
+		for _, v := range values {
+			if !isEmpty(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if f.op == "ne" {
+		return !(compareFilter{f.path, "eq", f.value}).match(res)
+	}
+	if f.value == nil {
+		return f.op == "eq" && len(values) == 0
+	}
+	for _, v := range values {
+		if compareValue(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// compareValue 字符串比较不区分大小写
+func compareValue(v any, op string, want any) bool {
+	switch w := want.(type) {
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		s, w = strings.ToLower(s), strings.ToLower(w)
+		switch op {
+		case "eq":
+			return s == w
+		case "co":
+			return strings.Contains(s, w)
+		case "sw":
+			return strings.HasPrefix(s, w)
+		case "ew":
+			return strings.HasSuffix(s, w)
+		case "gt":
+			return s > w
+		case "ge":
+			return s >= w
+		case "lt":
+			return s < w
+		case "le":
+			return s <= w
+		}
+	case bool:
+		b, ok := v.(bool)
+		return ok && op == "eq" && b == w
+	case float64:
+		n, ok := v.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return n == w
+		case "gt":
+			return n > w
+		case "ge":
+			return n >= w
+		case "lt":
+			return n < w
+		case "le":
+			return n <= w
+		}
+	}
+	return false
+}
+
+// attrPath 属性路径，如 userName、name.givenName、
+// urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber
+type attrPath struct {
+	urn  string // 扩展 schema，核心 schema 为空
+	attr string
+	sub  string
+}
+
+func parseAttrPath(s string) (attrPath, error) {
+	var p attrPath
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		i := strings.LastIndexByte(s, ':')
+		p.urn, s = s[:i], s[i+1:]
+		if isCoreSchema(p.urn) {
+			p.urn = ""
+		}
+	}
+	p.attr, p.sub, _ = strings.Cut(s, ".")
+	if p.attr == "" {
+		return p, badRequest(scimTypeInvalidPath, fmt.Sprintf("invalid attribute path %q", s))
+	}
+	return p, nil
+}
+
+// container 返回属性所在的对象，扩展属性位于以 schema URN 为键的子对象中
+func (p attrPath) container(res map[string]any) map[string]any {
+	if p.urn == "" {
+		return res
+	}
+	m, _ := getFold(res, p.urn).(map[string]any)
+	return m
+}
+
+// values 返回路径上的全部值，多值属性会展开
+func (p attrPath) values(res map[string]any) []any {
+	c := p.container(res)
+	if c == nil {
+		return nil
+	}
+	var out []any
+	for _, v := range flatten(getFold(c, p.attr)) {
+		if p.sub == "" {
+			out = append(out, v)
+			continue
+		}
+		if m, ok := v.(map[string]any); ok {
+			out = append(out, flatten(getFold(m, p.sub))...)
+		}
+	}
+	return out
+}
+
+func flatten(v any) []any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	}
+	return []any{v}
+}
+
+// getFold 按不区分大小写的键取值
+func getFold(m map[string]any, key string) any {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// keyFold 返回 m 中与 key 不区分大小写相等的键，不存在时返回 key
+func keyFold(m map[string]any, key string) string {
+	if _, ok := m[key]; ok {
+		return key
+	}
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
+
+// parseFilter 解析过滤表达式
+func parseFilter(s string) (filter, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{toks: toks}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.toks) {
+		return nil, badRequest(scimTypeInvalidFilter, fmt.Sprintf("unexpected %q in filter", p.toks[p.pos]))
+	}
+	return f, nil
+}
+
+type filterParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return badRequest(scimTypeInvalidFilter, fmt.Sprintf("expected %q in filter, got %q", tok, got))
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filter, error) {
+	switch tok := p.peek(); {
+	case strings.EqualFold(tok, "not"):
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{inner}, p.expect(")")
+	case tok == "(":
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	case tok == "":
+		return nil, badRequest(scimTypeInvalidFilter, "unexpected end of filter")
+	}
+
+	path, err := parseAttrPath(p.next())
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == "[" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return valuePathFilter{path: path, inner: inner}, p.expect("]")
+	}
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return compareFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, badRequest(scimTypeInvalidFilter, fmt.Sprintf("unknown operator %q", op))
+	}
+	raw := p.next()
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, badRequest(scimTypeInvalidFilter, fmt.Sprintf("invalid value %s in filter", raw))
+	}
+	return compareFilter{path: path, op: op, value: value}, nil
+}
+
+// tokenize 将过滤表达式切分为属性、运算符、值和括号
+func tokenize(s string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			toks = append(toks, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, badRequest(scimTypeInvalidFilter, "unterminated string in filter")
+			}
+			toks = append(toks, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n()[]\"", rune(s[j])) {
+				j++
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
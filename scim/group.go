@@ -0,0 +1,304 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/eryajf/go-alimail/alimail"
+)
+
+// 邮件组属性对应关系：
+//
+//	id           AliMail 邮件组ID
+//	displayName  邮件组名称
+//	members      成员，value 为用户ID；邮件组中的外部地址、嵌套邮件组不会出现在 members 中，也不会被修改
+//
+// 创建邮件组需要通过 WithGroupDomain 指定域名，地址为 displayName 转换后的 slug@domain。
+
+// Group SCIM 邮件组
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// memberIDs 成员的用户ID，去重
+func (g Group) memberIDs() []string {
+	var ids []string
+	for _, m := range g.Members {
+		if m.Value != "" && !slices.Contains(ids, m.Value) {
+			ids = append(ids, m.Value)
+		}
+	}
+	return ids
+}
+
+func (h *Handler) toSCIMGroup(ctx context.Context, rv *resolver, g alimail.Group, withMembers bool) (Group, error) {
+	res := Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          g.ID,
+		DisplayName: g.Name,
+		Meta:        &Meta{ResourceType: "Group", Location: h.location("Groups", g.ID)},
+	}
+	if !withMembers {
+		return res, nil
+	}
+	for m, err := range h.client.Group.AllMembers(ctx, g.ID) {
+		if err != nil {
+			return res, err
+		}
+		id, err := rv.userID(ctx, m.Email)
+		if err != nil {
+			return res, err
+		}
+		if id == "" {
+			continue
+		}
+		res.Members = append(res.Members, MultiValue{Value: id, Display: m.Name, Ref: h.location("Users", id)})
+	}
+	return res, nil
+}
+
+func (h *Handler) listGroups(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q, err := parseListQuery(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rv := h.newResolver()
+	if q.filter == nil {
+		rsp, err := h.client.Group.List(ctx, q.startIndex-1, max(q.count, 1))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		out := ListResponse{Schemas: []string{SchemaListResponse}, TotalResults: rsp.Total, StartIndex: q.startIndex, Resources: []any{}}
+		for _, g := range rsp.Groups[:min(len(rsp.Groups), q.count)] {
+			res, err := h.toSCIMGroup(ctx, rv, g, !q.excludeMembers)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			out.Resources = append(out.Resources, res)
+		}
+		out.ItemsPerPage = len(out.Resources)
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+
+	// 只有过滤条件涉及成员时才在过滤前读取成员，其余情况只为匹配的邮件组读取
+	withMembers := filtersMembers(q.filter)
+	var matched []any
+	for g, err := range h.client.Group.All(ctx) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		res, err := h.toSCIMGroup(ctx, rv, g, withMembers)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		m, err := toMap(res)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if !q.filter.match(m) {
+			continue
+		}
+		switch {
+		case q.excludeMembers:
+			res.Members = nil
+		case !withMembers:
+			if res, err = h.toSCIMGroup(ctx, rv, g, true); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		matched = append(matched, res)
+	}
+	writeJSON(w, http.StatusOK, q.page(matched))
+}
+
+// filtersMembers 过滤条件是否引用了 members
+func filtersMembers(f filter) bool {
+	switch f := f.(type) {
+	case logicalFilter:
+		return filtersMembers(f.left) || filtersMembers(f.right)
+	case notFilter:
+		return filtersMembers(f.inner)
+	case valuePathFilter:
+		return f.path.urn == "" && strings.EqualFold(f.path.attr, "members")
+	case compareFilter:
+		return f.path.urn == "" && strings.EqualFold(f.path.attr, "members")
+	}
+	return false
+}
+
+func (h *Handler) getGroup(w http.ResponseWriter, r *http.Request) {
+	g, err := h.client.Group.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	excludeMembers := false
+	for _, attr := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		excludeMembers = excludeMembers || strings.EqualFold(strings.TrimSpace(attr), "members")
+	}
+	h.writeGroup(w, r, h.newResolver(), g.ID, http.StatusOK, !excludeMembers)
+}
+
+// writeGroup 重新获取邮件组并输出
+func (h *Handler) writeGroup(w http.ResponseWriter, r *http.Request, rv *resolver, id string, status int, withMembers bool) {
+	g, err := h.client.Group.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := h.toSCIMGroup(r.Context(), rv, *g, withMembers)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if status == http.StatusCreated {
+		w.Header().Set("Location", res.Meta.Location)
+	}
+	writeJSON(w, status, res)
+}
+
+func (h *Handler) createGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if h.groupDomain == "" {
+		writeError(w, &Error{Status: http.StatusNotImplemented, Detail: "group creation is not enabled"})
+		return
+	}
+	var in Group
+	if err := decodeBody(r, &in); err != nil {
+		writeError(w, err)
+		return
+	}
+	name := strings.TrimSpace(in.DisplayName)
+	slug := alimail.Slugify(name)
+	if slug == "" {
+		writeError(w, badRequest(scimTypeInvalidValue, "displayName must contain letters or digits"))
+		return
+	}
+	rv := h.newResolver()
+	emails, err := rv.userEmails(ctx, in.memberIDs())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	created, err := h.client.Group.Create(ctx, alimail.CreateGroupReq{Name: name, Email: slug + "@" + h.groupDomain})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.client.Group.AddMembers(ctx, created.ID, emails); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.writeGroup(w, r, rv, created.ID, http.StatusCreated, true)
+}
+
+func (h *Handler) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	h.modifyGroup(w, r, func(current Group) (Group, error) {
+		var in Group
+		return in, decodeBody(r, &in)
+	})
+}
+
+func (h *Handler) patchGroup(w http.ResponseWriter, r *http.Request) {
+	h.modifyGroup(w, r, func(current Group) (Group, error) {
+		var req PatchRequest
+		if err := decodeBody(r, &req); err != nil {
+			return Group{}, err
+		}
+		m, err := toMap(current)
+		if err != nil {
+			return Group{}, err
+		}
+		if err := applyPatch(m, req.Operations); err != nil {
+			return Group{}, err
+		}
+		var patched Group
+		return patched, fromMap(m, &patched)
+	})
+}
+
+// modifyGroup 读取邮件组的当前表示，由 change 给出修改后的表示，再将差异写回 AliMail
+func (h *Handler) modifyGroup(w http.ResponseWriter, r *http.Request, change func(current Group) (Group, error)) {
+	ctx := r.Context()
+	g, err := h.client.Group.Get(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rv := h.newResolver()
+	current, err := h.toSCIMGroup(ctx, rv, *g, true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	in, err := change(current)
+	if err == nil {
+		err = h.updateGroup(ctx, rv, g, current, in)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Azure AD 要求 PATCH 返回 204 或完整资源，这里统一返回修改后的资源
+	h.writeGroup(w, r, rv, g.ID, http.StatusOK, true)
+}
+
+// updateGroup 修改名称并增删成员
+func (h *Handler) updateGroup(ctx context.Context, rv *resolver, g *alimail.Group, current, in Group) error {
+	if name := strings.TrimSpace(in.DisplayName); name == "" {
+		return badRequest(scimTypeInvalidValue, "displayName is required")
+	} else if name != g.Name {
+		if err := h.client.Group.Patch(ctx, alimail.PatchGroupReq{ID: g.ID, Name: &name}); err != nil {
+			return err
+		}
+	}
+	before, after := current.memberIDs(), in.memberIDs()
+	var added, removed []string
+	for _, id := range after {
+		if !slices.Contains(before, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range before {
+		if !slices.Contains(after, id) {
+			removed = append(removed, id)
+		}
+	}
+	addEmails, err := rv.userEmails(ctx, added)
+	if err != nil {
+		return err
+	}
+	if err := h.client.Group.AddMembers(ctx, g.ID, addEmails); err != nil {
+		return err
+	}
+	// 现有成员的邮箱已在读取当前表示时缓存
+	removeEmails, err := rv.userEmails(ctx, removed)
+	if err != nil {
+		return err
+	}
+	return h.client.Group.RemoveMembers(ctx, g.ID, removeEmails)
+}
+
+func (h *Handler) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.client.Group.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
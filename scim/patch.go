@@ -0,0 +1,276 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PatchOp 一个 PATCH 操作（RFC 7644 3.5.2）
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// PatchRequest PATCH 请求体
+type PatchRequest struct {
+	Schemas    []string  `json:"schemas"`
+	Operations []PatchOp `json:"Operations"`
+}
+
+// patchPath PATCH 的目标路径，如 members[value eq "2819c223"]、emails[type eq "work"].value
+type patchPath struct {
+	attrPath
+	filter filter // 值过滤，可为 nil
+}
+
+func parsePatchPath(s string) (patchPath, error) {
+	var pp patchPath
+	head, rest := s, ""
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		j := strings.LastIndexByte(s, ']')
+		if j < i {
+			return pp, badRequest(scimTypeInvalidPath, fmt.Sprintf("invalid path %q", s))
+		}
+		f, err := parseFilter(s[i+1 : j])
+		if err != nil {
+			return pp, err
+		}
+		pp.filter = f
+		head, rest = s[:i], s[j+1:]
+	}
+	ap, err := parseAttrPath(head)
+	if err != nil {
+		return pp, err
+	}
+	if rest != "" {
+		if !strings.HasPrefix(rest, ".") || ap.sub != "" {
+			return pp, badRequest(scimTypeInvalidPath, fmt.Sprintf("invalid path %q", s))
+		}
+		ap.sub = rest[1:]
+	}
+	pp.attrPath = ap
+	return pp, nil
+}
+
+// applyPatch 在资源的 JSON 表示上依次执行 PATCH 操作
+func applyPatch(res map[string]any, ops []PatchOp) error {
+	if len(ops) == 0 {
+		return badRequest(scimTypeInvalidSyntax, "no patch operations")
+	}
+	for _, op := range ops {
+		name := strings.ToLower(op.Op)
+		if name != "add" && name != "replace" && name != "remove" {
+			return badRequest(scimTypeInvalidSyntax, fmt.Sprintf("unknown patch op %q", op.Op))
+		}
+		if err := applyOp(res, name, op.Path, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOp(res map[string]any, op, path string, value any) error {
+	if path != "" {
+		pp, err := parsePatchPath(path)
+		if err != nil {
+			return err
+		}
+		return applyPath(res, op, pp, value)
+	}
+	if op == "remove" {
+		return badRequest(scimTypeNoTarget, "remove requires a path")
+	}
+	// 没有 path 时 value 为属性集合，键也可能是属性路径（如 Azure AD 发送的 "name.givenName"）
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return badRequest(scimTypeInvalidValue, "patch value must be an object when path is absent")
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, SchemaEnterpriseUser) {
+			ext, ok := v.(map[string]any)
+			if !ok {
+				return badRequest(scimTypeInvalidValue, k+" must be an object")
+			}
+			for ek, ev := range ext {
+				if err := applyOp(res, op, k+":"+ek, ev); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.EqualFold(k, "schemas") {
+			continue
+		}
+		if err := applyOp(res, op, k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyPath(res map[string]any, op string, pp patchPath, value any) error {
+	c := pp.container(res)
+	if c == nil {
+		if op == "remove" {
+			return nil
+		}
+		c = map[string]any{}
+		res[keyFold(res, pp.urn)] = c
+	}
+	key := keyFold(c, pp.attr)
+	if pp.filter != nil {
+		return applyFiltered(c, key, op, pp, value)
+	}
+	target, field := c, key
+	if pp.sub != "" {
+		parent, _ := c[key].(map[string]any)
+		if parent == nil {
+			if op == "remove" {
+				return nil
+			}
+			parent = map[string]any{}
+			c[key] = parent
+		}
+		target, field = parent, keyFold(parent, pp.sub)
+	}
+	switch op {
+	case "add":
+		target[field] = addValue(target[field], value)
+	case "replace":
+		target[field] = value
+	case "remove":
+		// Azure AD 移除成员时使用 path=members 并在 value 中给出要移除的成员
+		if values, ok := value.([]any); ok && len(values) > 0 {
+			target[field] = removeValues(flatten(target[field]), values)
+		} else {
+			delete(target, field)
+		}
+	}
+	return nil
+}
+
+// applyFiltered 对多值属性中满足过滤条件的元素执行操作
+func applyFiltered(c map[string]any, key, op string, pp patchPath, value any) error {
+	var (
+		kept    []any
+		matched bool
+	)
+	set := func(m map[string]any) error {
+		if pp.sub != "" {
+			m[keyFold(m, pp.sub)] = value
+			return nil
+		}
+		vm, ok := value.(map[string]any)
+		if !ok {
+			return badRequest(scimTypeInvalidValue, "value must be an object")
+		}
+		for k, v := range vm {
+			m[keyFold(m, k)] = v
+		}
+		return nil
+	}
+	for _, item := range flatten(c[key]) {
+		m, ok := item.(map[string]any)
+		if !ok || !pp.filter.match(m) {
+			kept = append(kept, item)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && pp.sub == "":
+			continue
+		case op == "remove":
+			delete(m, keyFold(m, pp.sub))
+		default:
+			if err := set(m); err != nil {
+				return err
+			}
+		}
+		kept = append(kept, m)
+	}
+	if !matched && op != "remove" {
+		// 目标元素不存在时按过滤条件新建，如 phoneNumbers[type eq "mobile"].value
+		m := map[string]any{}
+		if !seedElement(pp.filter, m) {
+			return badRequest(scimTypeNoTarget, fmt.Sprintf("no value of %s matches the filter", pp.attr))
+		}
+		if err := set(m); err != nil {
+			return err
+		}
+		kept = append(kept, m)
+	}
+	c[key] = kept
+	return nil
+}
+
+// seedElement 用过滤条件中的等值条件填充新元素，只支持 eq 与 and
+func seedElement(f filter, m map[string]any) bool {
+	switch f := f.(type) {
+	case compareFilter:
+		if f.op != "eq" || f.path.urn != "" || f.path.sub != "" {
+			return false
+		}
+		m[f.path.attr] = f.value
+		return true
+	case logicalFilter:
+		return f.and && seedElement(f.left, m) && seedElement(f.right, m)
+	}
+	return false
+}
+
+// addValue 多值属性追加（按 value 去重），对象合并，其它直接替换
+func addValue(old, value any) any {
+	oldList, oldIsList := old.([]any)
+	newList, newIsList := value.([]any)
+	if oldIsList || newIsList {
+		if !newIsList {
+			newList = []any{value}
+		}
+		out := append([]any(nil), oldList...)
+		for _, v := range newList {
+			if !containsValue(out, v) {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	if om, ok := old.(map[string]any); ok {
+		if nm, ok := value.(map[string]any); ok {
+			for k, v := range nm {
+				om[keyFold(om, k)] = v
+			}
+			return om
+		}
+	}
+	return value
+}
+
+// removeValues 移除 value 与 values 中任一元素相同的元素
+func removeValues(list, values []any) []any {
+	var out []any
+	for _, v := range list {
+		if !containsValue(values, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsValue(list []any, v any) bool {
+	key := elementValue(v)
+	for _, item := range list {
+		if strings.EqualFold(elementValue(item), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementValue 多值属性元素的值，复杂元素取其 value 子属性
+func elementValue(v any) string {
+	if m, ok := v.(map[string]any); ok {
+		v = getFold(m, "value")
+	}
+	return fmt.Sprint(v)
+}
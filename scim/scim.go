@@ -0,0 +1,324 @@
+// Package scim 提供 SCIM 2.0（RFC 7643/7644）服务端 HTTP Handler，
+// 将 /Users 与 /Groups 的请求转换为 AliMail 的用户、部门与邮件组接口调用，
+// 使 Okta、Azure AD（Entra ID）、Authing 等身份源可以直接通过本 SDK 开通和回收邮箱帐号。
+//
+//	h := scim.NewHandler(client, scim.WithBearerToken(token), scim.WithGroupDomain("example.com"))
+//	http.Handle("/scim/v2/", http.StripPrefix("/scim/v2", h))
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eryajf/go-alimail/alimail"
+)
+
+// SCIM schema URN
+const (
+	SchemaUser           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaEnterpriseUser = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SchemaListResponse   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp        = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError          = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaSPConfig       = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType   = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+func isCoreSchema(urn string) bool {
+	return strings.EqualFold(urn, SchemaUser) || strings.EqualFold(urn, SchemaGroup)
+}
+
+// MaxResults 单页最多返回的资源数，也是 ServiceProviderConfig 中声明的过滤结果上限
+const MaxResults = 100
+
+// Handler SCIM 2.0 服务端
+type Handler struct {
+	client         *alimail.Client
+	token          string
+	baseURL        string
+	groupDomain    string
+	freezeOnDelete bool
+	mux            *http.ServeMux
+}
+
+// Option Handler 的配置项
+type Option func(*Handler)
+
+// WithBearerToken 要求请求携带 Authorization: Bearer <token>，未设置时不做认证，需要由外层负责
+func WithBearerToken(token string) Option {
+	return func(h *Handler) { h.token = token }
+}
+
+// WithBaseURL 设置对外的 SCIM 地址（如 https://gw.example.com/scim/v2），用于资源的 meta.location
+func WithBaseURL(baseURL string) Option {
+	return func(h *Handler) { h.baseURL = strings.TrimSuffix(baseURL, "/") }
+}
+
+// WithGroupDomain 设置创建邮件组使用的域名，邮件组地址为 displayName 转换后的 slug@domain；
+// 未设置时不支持通过 SCIM 创建邮件组
+func WithGroupDomain(domain string) Option {
+	return func(h *Handler) { h.groupDomain = domain }
+}
+
+// WithFreezeOnDelete DELETE /Users/{id} 时冻结帐号而不是删除，保留邮箱数据
+func WithFreezeOnDelete() Option {
+	return func(h *Handler) { h.freezeOnDelete = true }
+}
+
+// NewHandler 创建 SCIM Handler，路由相对于挂载点，通常配合 http.StripPrefix 使用
+func NewHandler(client *alimail.Client, opts ...Option) *Handler {
+	h := &Handler{client: client, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /ServiceProviderConfig", h.serviceProviderConfig)
+	h.mux.HandleFunc("GET /ResourceTypes", h.resourceTypes)
+
+	h.mux.HandleFunc("GET /Users", h.listUsers)
+	h.mux.HandleFunc("POST /Users", h.createUser)
+	h.mux.HandleFunc("GET /Users/{id}", h.getUser)
+	h.mux.HandleFunc("PUT /Users/{id}", h.replaceUser)
+	h.mux.HandleFunc("PATCH /Users/{id}", h.patchUser)
+	h.mux.HandleFunc("DELETE /Users/{id}", h.deleteUser)
+
+	h.mux.HandleFunc("GET /Groups", h.listGroups)
+	h.mux.HandleFunc("POST /Groups", h.createGroup)
+	h.mux.HandleFunc("GET /Groups/{id}", h.getGroup)
+	h.mux.HandleFunc("PUT /Groups/{id}", h.replaceGroup)
+	h.mux.HandleFunc("PATCH /Groups/{id}", h.patchGroup)
+	h.mux.HandleFunc("DELETE /Groups/{id}", h.deleteGroup)
+	return h
+}
+
+// ServeHTTP 实现 http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeError(w, &Error{Status: http.StatusUnauthorized, Detail: "invalid bearer token"})
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) location(resource, id string) string {
+	return h.baseURL + "/" + resource + "/" + id
+}
+
+// scimType 错误类型（RFC 7644 3.12）
+const (
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeInvalidValue  = "invalidValue"
+	scimTypeInvalidSyntax = "invalidSyntax"
+	scimTypeNoTarget      = "noTarget"
+	scimTypeMutability    = "mutability"
+	scimTypeUniqueness    = "uniqueness"
+)
+
+// Error SCIM 错误响应
+type Error struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	if e.ScimType != "" {
+		return fmt.Sprintf("scim %d %s: %s", e.Status, e.ScimType, e.Detail)
+	}
+	return fmt.Sprintf("scim %d: %s", e.Status, e.Detail)
+}
+
+func badRequest(scimType, detail string) *Error {
+	return &Error{Status: http.StatusBadRequest, ScimType: scimType, Detail: detail}
+}
+
+// isNotFound AliMail 接口是否返回了 404
+func isNotFound(err error) bool {
+	var apiErr *alimail.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// writeError 按 SCIM 格式输出错误，AliMail 接口错误沿用其 HTTP 状态码，密码不满足策略时返回 400
+func writeError(w http.ResponseWriter, err error) {
+	var (
+		scimErr   *Error
+		apiErr    *alimail.APIError
+		policyErr *alimail.PasswordPolicyError
+	)
+	switch {
+	case errors.As(err, &scimErr):
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 400:
+		scimErr = &Error{Status: apiErr.StatusCode, Detail: apiErr.Message}
+		if apiErr.StatusCode == http.StatusConflict {
+			scimErr.ScimType = scimTypeUniqueness
+		}
+	case errors.As(err, &policyErr):
+		scimErr = badRequest(scimTypeInvalidValue, policyErr.Error())
+	default:
+		scimErr = &Error{Status: http.StatusInternalServerError, Detail: err.Error()}
+	}
+	body := map[string]any{
+		"schemas": []string{SchemaError},
+		"status":  strconv.Itoa(scimErr.Status),
+		"detail":  scimErr.Detail,
+	}
+	if scimErr.ScimType != "" {
+		body["scimType"] = scimErr.ScimType
+	}
+	writeJSON(w, scimErr.Status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// decodeBody 解析请求体，字段名不区分大小写
+func decodeBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return badRequest(scimTypeInvalidSyntax, fmt.Sprintf("invalid request body: %v", err))
+	}
+	return nil
+}
+
+// ListResponse 列表查询的返回
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// listQuery 列表查询参数
+type listQuery struct {
+	filter         filter
+	startIndex     int // 从 1 开始
+	count          int
+	excludeMembers bool // excludedAttributes=members，Azure AD 查询邮件组时使用
+}
+
+func parseListQuery(r *http.Request) (listQuery, error) {
+	q := listQuery{startIndex: 1, count: MaxResults}
+	v := r.URL.Query()
+	if s := v.Get("startIndex"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return q, badRequest(scimTypeInvalidValue, "invalid startIndex")
+		}
+		q.startIndex = max(n, 1)
+	}
+	if s := v.Get("count"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return q, badRequest(scimTypeInvalidValue, "invalid count")
+		}
+		q.count = min(max(n, 0), MaxResults)
+	}
+	if s := v.Get("filter"); s != "" {
+		f, err := parseFilter(s)
+		if err != nil {
+			return q, err
+		}
+		q.filter = f
+	}
+	for _, attr := range strings.Split(v.Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			q.excludeMembers = true
+		}
+	}
+	return q, nil
+}
+
+// page 对已过滤的全部资源分页
+func (q listQuery) page(all []any) ListResponse {
+	rsp := ListResponse{Schemas: []string{SchemaListResponse}, TotalResults: len(all), StartIndex: q.startIndex, Resources: []any{}}
+	if start := q.startIndex - 1; start < len(all) {
+		rsp.Resources = all[start:min(start+q.count, len(all))]
+	}
+	rsp.ItemsPerPage = len(rsp.Resources)
+	return rsp
+}
+
+// toMap 将资源转换为通用的 JSON 对象，用于过滤和 PATCH
+func toMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	return m, json.Unmarshal(data, &m)
+}
+
+// fromMap 将通用的 JSON 对象转换回资源
+func fromMap(m map[string]any, v any) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return badRequest(scimTypeInvalidValue, err.Error())
+	}
+	return nil
+}
+
+func (h *Handler) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(ok bool) map[string]any { return map[string]any{"supported": ok} }
+	writeJSON(w, http.StatusOK, map[string]any{
+		"schemas":        []string{SchemaSPConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": MaxResults},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication scheme using the OAuth Bearer Token Standard",
+			"primary":     true,
+		}},
+	})
+}
+
+func (h *Handler) resourceTypes(w http.ResponseWriter, r *http.Request) {
+	types := []any{
+		map[string]any{
+			"schemas":  []string{SchemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   SchemaUser,
+			"schemaExtensions": []map[string]any{
+				{"schema": SchemaEnterpriseUser, "required": false},
+			},
+		},
+		map[string]any{
+			"schemas":  []string{SchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   SchemaGroup,
+		},
+	}
+	writeJSON(w, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(types),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	})
+}
@@ -0,0 +1,424 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eryajf/go-alimail/alimail"
+)
+
+// fakeAliMail 保存用户、部门和邮件组状态的假服务
+type fakeAliMail struct {
+	mu      sync.Mutex
+	users   map[string]alimail.User // 按用户ID
+	depts   map[string]alimail.Department
+	groups  map[string]alimail.Group
+	members map[string][]string // 邮件组ID -> 成员邮箱
+	resets  []string
+}
+
+func newFakeAliMail() *fakeAliMail {
+	return &fakeAliMail{
+		users:   map[string]alimail.User{},
+		depts:   map[string]alimail.Department{},
+		groups:  map[string]alimail.Group{},
+		members: map[string][]string{},
+	}
+}
+
+func (f *fakeAliMail) addUser(u alimail.User) {
+	if u.Status == "" {
+		u.Status = alimail.NORMAL
+	}
+	f.users[u.ID] = u
+}
+
+func (f *fakeAliMail) user(key string) (alimail.User, bool) {
+	if u, ok := f.users[key]; ok {
+		return u, true
+	}
+	for _, u := range f.users {
+		if strings.EqualFold(u.Email, key) {
+			return u, true
+		}
+	}
+	return alimail.User{}, false
+}
+
+func notFoundJSON(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"message":"not found"}`)
+}
+
+func (f *fakeAliMail) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.HasSuffix(r.URL.Path, "/token") {
+		json.NewEncoder(w).Encode(alimail.TokenResponse{TokenType: "bearer", AccessToken: "t", ExpiresIn: 3600})
+		return
+	}
+	enc := json.NewEncoder(w)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	switch {
+	case r.URL.Path == "/v2/users/listByIds":
+		var req struct{ IDs []string }
+		json.NewDecoder(r.Body).Decode(&req)
+		var users []alimail.User
+		for _, id := range req.IDs {
+			if u, ok := f.users[id]; ok {
+				users = append(users, u)
+			}
+		}
+		enc.Encode(map[string]any{"users": users})
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/users":
+		var list []alimail.User
+		for _, u := range f.users {
+			list = append(list, u)
+		}
+		slices.SortFunc(list, func(a, b alimail.User) int { return strings.Compare(a.ID, b.ID) })
+		enc.Encode(alimail.ListUsersRsp{Users: list[min(offset, len(list)):], Total: len(list)})
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/users":
+		var u alimail.User
+		json.NewDecoder(r.Body).Decode(&u)
+		u.ID = fmt.Sprintf("u%d", len(f.users)+1)
+		f.addUser(u)
+		enc.Encode(u)
+	case len(parts) == 4 && parts[1] == "users" && parts[3] == "resetPassword":
+		f.resets = append(f.resets, parts[2])
+	case len(parts) == 3 && parts[1] == "users":
+		u, ok := f.user(parts[2])
+		if !ok {
+			notFoundJSON(w)
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			json.NewDecoder(r.Body).Decode(&u)
+			if m, ok := f.user(u.ManagerEmail); ok {
+				u.ManagerInfo.ID, u.ManagerInfo.Email, u.ManagerInfo.Name = m.ID, m.Email, m.Name
+			}
+			f.users[u.ID] = u
+		case http.MethodDelete:
+			delete(f.users, u.ID)
+			return
+		}
+		enc.Encode(u)
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/departments":
+		var d alimail.Department
+		json.NewDecoder(r.Body).Decode(&d)
+		d.ID = fmt.Sprintf("d%d", len(f.depts)+1)
+		f.depts[d.ID] = d
+		enc.Encode(d)
+	case len(parts) == 4 && parts[1] == "departments" && parts[3] == "departments":
+		var children []alimail.Department
+		for _, d := range f.depts {
+			if d.ParentID == parts[2] {
+				children = append(children, d)
+			}
+		}
+		enc.Encode(alimail.ListDepartmentDeptsRsp{Departments: children, Total: len(children)})
+	case len(parts) == 3 && parts[1] == "departments":
+		d, ok := f.depts[parts[2]]
+		if !ok {
+			notFoundJSON(w)
+			return
+		}
+		enc.Encode(d)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/groups":
+		var list []alimail.Group
+		for _, g := range f.groups {
+			list = append(list, g)
+		}
+		slices.SortFunc(list, func(a, b alimail.Group) int { return strings.Compare(a.ID, b.ID) })
+		enc.Encode(alimail.ListGroupsRsp{Groups: list[min(offset, len(list)):], Total: len(list)})
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/groups":
+		var g alimail.Group
+		json.NewDecoder(r.Body).Decode(&g)
+		g.ID = fmt.Sprintf("g%d", len(f.groups)+1)
+		f.groups[g.ID] = g
+		enc.Encode(g)
+	case len(parts) == 4 && parts[1] == "groups" && parts[3] == "members":
+		id := parts[2]
+		var req struct{ Members []alimail.GroupMember }
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.Method {
+		case http.MethodGet:
+			var members []alimail.GroupMember
+			for _, email := range f.members[id] {
+				m := alimail.GroupMember{Email: email}
+				if u, ok := f.user(email); ok {
+					m.Name = u.Name
+				}
+				members = append(members, m)
+			}
+			enc.Encode(alimail.ListGroupMembersRsp{Members: members[min(offset, len(members)):], Total: len(members)})
+		case http.MethodPost:
+			for _, m := range req.Members {
+				f.members[id] = append(f.members[id], m.Email)
+			}
+		case http.MethodDelete:
+			f.members[id] = slices.DeleteFunc(f.members[id], func(email string) bool {
+				return slices.ContainsFunc(req.Members, func(m alimail.GroupMember) bool { return m.Email == email })
+			})
+		}
+	case len(parts) == 3 && parts[1] == "groups":
+		g, ok := f.groups[parts[2]]
+		if !ok {
+			notFoundJSON(w)
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			json.NewDecoder(r.Body).Decode(&g)
+			f.groups[g.ID] = g
+		case http.MethodDelete:
+			delete(f.groups, g.ID)
+			return
+		}
+		enc.Encode(g)
+	default:
+		http.Error(w, `{"message":"unexpected request"}`, http.StatusBadRequest)
+	}
+}
+
+func newTestHandler(t *testing.T, opts ...Option) (http.Handler, *fakeAliMail) {
+	t.Helper()
+	f := newFakeAliMail()
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	c := alimail.NewClient("id", "secret")
+	c.SetBaseURL(srv.URL)
+	return NewHandler(c, opts...), f
+}
+
+// do 发送请求并解析返回的 JSON
+func do(t *testing.T, h http.Handler, method, target, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var out map[string]any
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("%s %s: invalid response %q", method, target, rec.Body.String())
+		}
+	}
+	return rec.Code, out
+}
+
+func TestParseFilter(t *testing.T) {
+	res := map[string]any{
+		"userName": "Alice@Example.com",
+		"active":   true,
+		"emails":   []any{map[string]any{"value": "alice@example.com", "type": "work"}},
+		SchemaEnterpriseUser: map[string]any{
+			"department": "研发中心/平台部",
+		},
+	}
+	cases := []struct {
+		filter string
+		want   bool
+	}{
+		{`userName eq "alice@example.com"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "alice"`, true},
+		{`emails[type eq "work" and value ew "@example.com"]`, true},
+		{`emails.value co "bob"`, false},
+		{`active eq true and not (title pr)`, true},
+		{`title pr or active eq false`, false},
+		{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department sw "研发中心"`, true},
+	}
+	for _, c := range cases {
+		f, err := parseFilter(c.filter)
+		if err != nil {
+			t.Errorf("parseFilter(%q): %v", c.filter, err)
+			continue
+		}
+		if got := f.match(res); got != c.want {
+			t.Errorf("%q matched %v, want %v", c.filter, got, c.want)
+		}
+	}
+	for _, bad := range []string{`userName eq`, `userName xx "a"`, `(userName pr`, `userName eq "a`} {
+		if _, err := parseFilter(bad); err == nil {
+			t.Errorf("parseFilter(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	h, _ := newTestHandler(t, WithBearerToken("secret"))
+	req := httptest.NewRequest(http.MethodGet, "/Users", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if code, _ := do(t, h, http.MethodGet, "/ServiceProviderConfig", ""); code != http.StatusOK {
+		t.Errorf("authorized status = %d", code)
+	}
+}
+
+func TestUserProvisioning(t *testing.T) {
+	h, f := newTestHandler(t, WithBearerToken("secret"))
+	f.addUser(alimail.User{ID: "u1", Email: "boss@example.com", Name: "Boss"})
+
+	code, created := do(t, h, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "alice@example.com",
+		"name": {"givenName": "Alice", "familyName": "Liu"},
+		"title": "Engineer",
+		"phoneNumbers": [{"value": "13800000000", "type": "mobile"}],
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+			"department": "研发中心/平台部",
+			"manager": {"value": "u1"}
+		}
+	}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create status = %d %v", code, created)
+	}
+	id := created["id"].(string)
+	u := f.users[id]
+	if u.Name != "Alice Liu" || u.ManagerEmail != "boss@example.com" || u.Phone != "13800000000" || len(f.depts) != 2 {
+		t.Errorf("created user = %+v, depts %v", u, f.depts)
+	}
+	if ext := created[SchemaEnterpriseUser].(map[string]any); ext["department"] != "研发中心/平台部" {
+		t.Errorf("enterprise = %v", ext)
+	}
+	if code, _ := do(t, h, http.MethodPost, "/Users", `{"userName": "ALICE@example.com"}`); code != http.StatusConflict {
+		t.Errorf("duplicate create status = %d", code)
+	}
+
+	_, list := do(t, h, http.MethodGet, `/Users?filter=userName+eq+%22alice@example.com%22`, "")
+	if list["totalResults"] != float64(1) || list["Resources"].([]any)[0].(map[string]any)["id"] != id {
+		t.Errorf("filtered list = %v", list)
+	}
+	code, rejected := do(t, h, http.MethodGet, `/Users?filter=title+eq+%22Engineer%22`, "")
+	if code != http.StatusBadRequest || rejected["scimType"] != "invalidFilter" {
+		t.Errorf("unsupported filter = %d %v", code, rejected)
+	}
+	_, list = do(t, h, http.MethodGet, "/Users?startIndex=2&count=1", "")
+	if list["totalResults"] != float64(2) || list["itemsPerPage"] != float64(1) {
+		t.Errorf("paged list = %v", list)
+	}
+
+	// Azure AD 以字符串形式发送 active，并用不带 path 的属性路径修改姓名
+	code, patched := do(t, h, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "value": {"name.givenName": "Alicia", "name.familyName": "Liu"}},
+			{"op": "add", "path": "phoneNumbers[type eq \"work\"].value", "value": "010-1234"}
+		]
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("patch status = %d %v", code, patched)
+	}
+	u = f.users[id]
+	if u.Status != alimail.FREEZE || u.Name != "Alicia Liu" || u.WorkPhone != "010-1234" || u.Phone != "13800000000" {
+		t.Errorf("patched user = %+v", u)
+	}
+	if patched["active"] != false {
+		t.Errorf("patched active = %v", patched["active"])
+	}
+
+	// 不带企业扩展的 PUT 不会清除部门和上级
+	code, _ = do(t, h, http.MethodPut, "/Users/"+id, `{"userName": "alice@example.com", "displayName": "Alice", "active": true, "password": "N3w-Passw0rd!x"}`)
+	if code != http.StatusOK {
+		t.Fatalf("put status = %d", code)
+	}
+	u = f.users[id]
+	if u.Status != alimail.NORMAL || u.Name != "Alice" || u.ManagerEmail != "boss@example.com" || len(u.DepartmentIds) != 1 || len(f.resets) != 1 {
+		t.Errorf("replaced user = %+v, resets %v", u, f.resets)
+	}
+	// 企业扩展中没有 department 时不调整部门
+	depts := slices.Clone(u.DepartmentIds)
+	code, _ = do(t, h, http.MethodPut, "/Users/"+id, `{
+		"userName": "alice@example.com",
+		"displayName": "Alice",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "E42", "manager": {"value": "u1"}}
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("put enterprise status = %d", code)
+	}
+	if u = f.users[id]; u.EmployeeNo != "E42" || !slices.Equal(u.DepartmentIds, depts) {
+		t.Errorf("put enterprise user = %+v, want departments %v", u, depts)
+	}
+	if code, _ := do(t, h, http.MethodPut, "/Users/"+id, `{"userName": "bob@example.com"}`); code != http.StatusBadRequest {
+		t.Errorf("rename status = %d", code)
+	}
+
+	if code, _ := do(t, h, http.MethodDelete, "/Users/"+id, ""); code != http.StatusNoContent {
+		t.Errorf("delete status = %d", code)
+	}
+	if code, body := do(t, h, http.MethodGet, "/Users/"+id, ""); code != http.StatusNotFound || body["status"] != "404" {
+		t.Errorf("get deleted = %d %v", code, body)
+	}
+}
+
+func TestGroupProvisioning(t *testing.T) {
+	h, f := newTestHandler(t, WithBearerToken("secret"), WithGroupDomain("example.com"))
+	f.addUser(alimail.User{ID: "u1", Email: "alice@example.com", Name: "Alice"})
+	f.addUser(alimail.User{ID: "u2", Email: "bob@example.com", Name: "Bob"})
+	f.addUser(alimail.User{ID: "u3", Email: "carol@example.com", Name: "Carol"})
+
+	code, created := do(t, h, http.MethodPost, "/Groups", `{"displayName": "Platform Team", "members": [{"value": "u1"}]}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create status = %d %v", code, created)
+	}
+	id := created["id"].(string)
+	if g := f.groups[id]; g.Email != "platform-team@example.com" {
+		t.Errorf("group email = %q", g.Email)
+	}
+	// 外部地址不会出现在 SCIM 成员中，也不会被修改
+	f.members[id] = append(f.members[id], "partner@other.com")
+
+	code, patched := do(t, h, http.MethodPatch, "/Groups/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "add", "path": "members", "value": [{"value": "u2"}, {"value": "u3"}]},
+			{"op": "remove", "path": "members[value eq \"u1\"]"},
+			{"op": "replace", "path": "displayName", "value": "Platform"}
+		]
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("patch status = %d %v", code, patched)
+	}
+	want := []string{"partner@other.com", "bob@example.com", "carol@example.com"}
+	if !slices.Equal(f.members[id], want) || f.groups[id].Name != "Platform" {
+		t.Errorf("members = %v, name %q", f.members[id], f.groups[id].Name)
+	}
+	if len(patched["members"].([]any)) != 2 {
+		t.Errorf("patched members = %v", patched["members"])
+	}
+
+	// Azure AD 移除成员时在 value 中给出成员
+	do(t, h, http.MethodPatch, "/Groups/"+id, `{"Operations": [{"op": "remove", "path": "members", "value": [{"value": "u2"}]}]}`)
+	if want := []string{"partner@other.com", "carol@example.com"}; !slices.Equal(f.members[id], want) {
+		t.Errorf("members after remove = %v", f.members[id])
+	}
+
+	_, list := do(t, h, http.MethodGet, `/Groups?filter=displayName+eq+%22platform%22&excludedAttributes=members`, "")
+	res := list["Resources"].([]any)
+	if len(res) != 1 || res[0].(map[string]any)["members"] != nil {
+		t.Errorf("filtered groups = %v", list)
+	}
+	_, list = do(t, h, http.MethodGet, `/Groups?filter=members[value+eq+%22u3%22]`, "")
+	if list["totalResults"] != float64(1) {
+		t.Errorf("groups with member = %v", list)
+	}
+	if code, _ := do(t, h, http.MethodPatch, "/Groups/"+id, `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "nobody"}]}]}`); code != http.StatusBadRequest {
+		t.Errorf("unknown member status = %d", code)
+	}
+	if code, _ := do(t, h, http.MethodDelete, "/Groups/"+id, ""); code != http.StatusNoContent || len(f.groups) != 0 {
+		t.Errorf("delete status = %d", code)
+	}
+}
@@ -0,0 +1,663 @@
+package scim
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eryajf/go-alimail/alimail"
+)
+
+// 用户属性对应关系：
+//
+//	id                    AliMail 用户ID
+//	userName、emails       邮箱（创建后不可修改）
+//	displayName、name      姓名
+//	nickName              昵称
+//	title                 职位
+//	active                false 表示帐号被冻结
+//	phoneNumbers          type 为 mobile 的为手机号，work 为工作电话
+//	enterprise 扩展        employeeNumber 为员工编号，department 为部门名称路径（不存在时创建，
+//	                      没有 department 时不调整部门），manager.value 为上级的用户ID
+//
+// AliMail 没有可以保存 externalId 的字段，externalId 会被忽略。
+// 查询用户时只支持 userName eq 与 id eq 过滤条件（可以用 and 组合其它条件），其它条件返回 invalidFilter。
+
+// Name 姓名
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// MultiValue 多值属性的元素，如 emails、phoneNumbers、members
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// Manager 企业扩展中的上级
+type Manager struct {
+	Value       string `json:"value,omitempty"` // 上级的用户ID
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// EnterpriseUser 企业用户扩展
+type EnterpriseUser struct {
+	EmployeeNumber string   `json:"employeeNumber,omitempty"`
+	Department     string   `json:"department,omitempty"` // 部门名称路径，如 "研发中心/平台部"
+	Manager        *Manager `json:"manager,omitempty"`
+}
+
+// Meta 资源元数据
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// User SCIM 用户
+type User struct {
+	Schemas      []string        `json:"schemas"`
+	ID           string          `json:"id,omitempty"`
+	ExternalID   string          `json:"externalId,omitempty"`
+	UserName     string          `json:"userName"`
+	Name         *Name           `json:"name,omitempty"`
+	DisplayName  string          `json:"displayName,omitempty"`
+	NickName     string          `json:"nickName,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Active       *bool           `json:"active,omitempty"`
+	Password     string          `json:"password,omitempty"`
+	Emails       []MultiValue    `json:"emails,omitempty"`
+	PhoneNumbers []MultiValue    `json:"phoneNumbers,omitempty"`
+	Enterprise   *EnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta         *Meta           `json:"meta,omitempty"`
+}
+
+// fullName 按 displayName、name.formatted、givenName familyName 的顺序取姓名
+func (u User) fullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+}
+
+// phone 返回指定类型的电话，没有类型的电话视为手机号
+func (u User) phone(typ string) string {
+	for _, p := range u.PhoneNumbers {
+		if strings.EqualFold(p.Type, typ) || p.Type == "" && typ == "mobile" {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func (u User) enterprise() EnterpriseUser {
+	if u.Enterprise == nil {
+		return EnterpriseUser{}
+	}
+	return *u.Enterprise
+}
+
+func (u User) managerID() string {
+	if m := u.enterprise().Manager; m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+// normalizeUser 兼容身份源的非标准写法：active 为字符串 "True"/"False"（Azure AD），manager 直接为用户ID
+func normalizeUser(m map[string]any) {
+	if k := keyFold(m, "active"); m[k] != nil {
+		if s, ok := m[k].(string); ok {
+			if b, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+				m[k] = b
+			}
+		}
+	}
+	if ext, ok := getFold(m, SchemaEnterpriseUser).(map[string]any); ok {
+		k := keyFold(ext, "manager")
+		if s, ok := ext[k].(string); ok {
+			ext[k] = map[string]any{"value": s}
+		}
+	}
+}
+
+// decodeUser 解析请求体中的用户
+func decodeUser(r *http.Request) (User, error) {
+	var m map[string]any
+	if err := decodeBody(r, &m); err != nil {
+		return User{}, err
+	}
+	normalizeUser(m)
+	var u User
+	return u, fromMap(m, &u)
+}
+
+// resolver 在一次请求内缓存部门路径与用户ID、邮箱的对应关系
+type resolver struct {
+	client    *alimail.Client
+	deptPaths map[string]string
+	ids       map[string]string // 小写邮箱 -> 用户ID，空字符串表示不是用户
+	emails    map[string]string // 用户ID -> 邮箱
+}
+
+func (h *Handler) newResolver() *resolver {
+	return &resolver{client: h.client, deptPaths: map[string]string{}, ids: map[string]string{}, emails: map[string]string{}}
+}
+
+// departmentPath 返回部门的名称路径，不含根部门
+func (rv *resolver) departmentPath(ctx context.Context, id string) (string, error) {
+	if id == "" || id == alimail.RootDepartmentID {
+		return "", nil
+	}
+	if p, ok := rv.deptPaths[id]; ok {
+		return p, nil
+	}
+	dept, err := rv.client.Department.GetDepartment(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	var path string
+	if dept.ParentID != "" {
+		parent, err := rv.departmentPath(ctx, dept.ParentID)
+		if err != nil {
+			return "", err
+		}
+		path = strings.TrimPrefix(parent+"/"+dept.Name, "/")
+	}
+	rv.deptPaths[id] = path
+	return path, nil
+}
+
+// userID 返回邮箱对应的用户ID，不是用户（如外部地址、邮件组）时返回空字符串
+func (rv *resolver) userID(ctx context.Context, email string) (string, error) {
+	key := strings.ToLower(email)
+	if id, ok := rv.ids[key]; ok {
+		return id, nil
+	}
+	u, err := rv.client.User.Get(ctx, alimail.BaseUserReq{Email: email})
+	if err != nil && !isNotFound(err) {
+		return "", err
+	}
+	var id string
+	if u != nil {
+		id = u.ID
+		rv.emails[id] = u.Email
+	}
+	rv.ids[key] = id
+	return id, nil
+}
+
+// userEmails 返回用户ID对应的邮箱，有不存在的用户时返回 invalidValue 错误
+func (rv *resolver) userEmails(ctx context.Context, ids []string) ([]string, error) {
+	var missing []string
+	for _, id := range ids {
+		if _, ok := rv.emails[id]; !ok && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		users, notFound, err := rv.client.User.ListAllByIds(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		if len(notFound) > 0 {
+			return nil, badRequest(scimTypeInvalidValue, "unknown user id "+strings.Join(notFound, ", "))
+		}
+		for _, u := range users {
+			rv.emails[u.ID] = u.Email
+			rv.ids[strings.ToLower(u.Email)] = u.ID
+		}
+	}
+	emails := make([]string, len(ids))
+	for i, id := range ids {
+		emails[i] = rv.emails[id]
+	}
+	return emails, nil
+}
+
+func (h *Handler) toSCIMUser(ctx context.Context, rv *resolver, u alimail.User) (User, error) {
+	rv.emails[u.ID] = u.Email
+	rv.ids[strings.ToLower(u.Email)] = u.ID
+	res := User{
+		Schemas:     []string{SchemaUser, SchemaEnterpriseUser},
+		ID:          u.ID,
+		UserName:    u.Email,
+		Name:        &Name{Formatted: u.Name},
+		DisplayName: u.Name,
+		NickName:    u.Nickname,
+		Title:       u.JobTitle,
+		Active:      alimail.Ptr(u.Status != alimail.FREEZE),
+		Emails:      []MultiValue{{Value: u.Email, Type: "work", Primary: true}},
+		Meta:        &Meta{ResourceType: "User", Location: h.location("Users", u.ID)},
+	}
+	if u.Phone != "" {
+		res.PhoneNumbers = append(res.PhoneNumbers, MultiValue{Value: u.Phone, Type: "mobile"})
+	}
+	if u.WorkPhone != "" {
+		res.PhoneNumbers = append(res.PhoneNumbers, MultiValue{Value: u.WorkPhone, Type: "work"})
+	}
+	if !u.CreatedTime.IsZero() {
+		res.Meta.Created = u.CreatedTime.Format(time.RFC3339)
+	}
+	ext := EnterpriseUser{EmployeeNumber: u.EmployeeNo}
+	if len(u.DepartmentIds) > 0 {
+		path, err := rv.departmentPath(ctx, u.DepartmentIds[0])
+		if err != nil {
+			return res, err
+		}
+		ext.Department = path
+	}
+	if u.ManagerInfo.ID != "" {
+		ext.Manager = &Manager{Value: u.ManagerInfo.ID, DisplayName: u.ManagerInfo.Name}
+	}
+	if ext != (EnterpriseUser{}) {
+		res.Enterprise = &ext
+	}
+	return res, nil
+}
+
+// lookupUser 按 userName eq 或 id eq 过滤时直接查询单个用户，返回 false 表示需要遍历全部用户
+func (h *Handler) lookupUser(ctx context.Context, f filter) ([]alimail.User, bool, error) {
+	attr, value, ok := equalityKey(f, "userName", "id")
+	if !ok {
+		return nil, false, nil
+	}
+	req := alimail.BaseUserReq{Email: value}
+	if attr == "id" {
+		req = alimail.BaseUserReq{ID: value}
+	}
+	u, err := h.client.User.Get(ctx, req)
+	if isNotFound(err) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	return []alimail.User{*u}, true, nil
+}
+
+// equalityKey 找出过滤条件中（含 and 的任一侧）对 attrs 之一的 eq 字符串比较
+func equalityKey(f filter, attrs ...string) (string, string, bool) {
+	switch f := f.(type) {
+	case compareFilter:
+		s, ok := f.value.(string)
+		if !ok || f.op != "eq" || f.path.urn != "" || f.path.sub != "" {
+			return "", "", false
+		}
+		for _, a := range attrs {
+			if strings.EqualFold(f.path.attr, a) {
+				return a, s, true
+			}
+		}
+	case logicalFilter:
+		if !f.and {
+			return "", "", false
+		}
+		if a, v, ok := equalityKey(f.left, attrs...); ok {
+			return a, v, true
+		}
+		return equalityKey(f.right, attrs...)
+	}
+	return "", "", false
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q, err := parseListQuery(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rv := h.newResolver()
+	if q.filter == nil {
+		rsp, err := h.client.User.List(ctx, alimail.ListUsersReq{Offset: q.startIndex - 1, Limit: max(q.count, 1)})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		out := ListResponse{Schemas: []string{SchemaListResponse}, TotalResults: rsp.Total, StartIndex: q.startIndex, Resources: []any{}}
+		for _, u := range rsp.Users[:min(len(rsp.Users), q.count)] {
+			res, err := h.toSCIMUser(ctx, rv, u)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			out.Resources = append(out.Resources, res)
+		}
+		out.ItemsPerPage = len(out.Resources)
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+
+	// 只支持能直接查询单个用户的过滤条件，其它条件需要在每次请求时读取全部用户与部门，代价过高
+	users, ok, err := h.lookupUser(ctx, q.filter)
+	if err == nil && !ok {
+		err = badRequest(scimTypeInvalidFilter, "only userName eq and id eq filters are supported for users")
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var matched []any
+	for _, u := range users {
+		res, err := h.toSCIMUser(ctx, rv, u)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		m, err := toMap(res)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if q.filter.match(m) {
+			matched = append(matched, res)
+		}
+	}
+	writeJSON(w, http.StatusOK, q.page(matched))
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.client.User.Get(r.Context(), alimail.BaseUserReq{ID: r.PathValue("id")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.writeUser(w, r, h.newResolver(), u.ID, http.StatusOK)
+}
+
+// writeUser 重新获取用户并输出
+func (h *Handler) writeUser(w http.ResponseWriter, r *http.Request, rv *resolver, id string, status int) {
+	u, err := h.client.User.Get(r.Context(), alimail.BaseUserReq{ID: id})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := h.toSCIMUser(r.Context(), rv, *u)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if status == http.StatusCreated {
+		w.Header().Set("Location", res.Meta.Location)
+	}
+	writeJSON(w, status, res)
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	in, err := decodeUser(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	email := strings.TrimSpace(in.UserName)
+	if email == "" {
+		writeError(w, badRequest(scimTypeInvalidValue, "userName is required"))
+		return
+	}
+	if _, err := h.client.User.Get(ctx, alimail.BaseUserReq{Email: email}); err == nil {
+		writeError(w, &Error{Status: http.StatusConflict, ScimType: scimTypeUniqueness, Detail: "user " + email + " already exists"})
+		return
+	} else if !isNotFound(err) {
+		writeError(w, err)
+		return
+	}
+	name := in.fullName()
+	if name == "" {
+		name = email
+	}
+	rv := h.newResolver()
+	ext := in.enterprise()
+	deptID, err := h.client.Department.EnsurePath(ctx, ext.Department)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var manager string
+	if id := in.managerID(); id != "" {
+		emails, err := rv.userEmails(ctx, []string{id})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		manager = emails[0]
+	}
+	password := in.Password
+	if password == "" {
+		if password, err = h.client.PasswordPolicy().Generate(alimail.PasswordContext{Name: name, Email: email}); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	created, err := h.client.User.Create(ctx, alimail.CreateUserReq{
+		Email:                         email,
+		Password:                      password,
+		Name:                          name,
+		Nickname:                      in.NickName,
+		EmployeeNo:                    ext.EmployeeNumber,
+		JobTitle:                      in.Title,
+		DepartmentIds:                 []string{deptID},
+		Phone:                         in.phone("mobile"),
+		WorkPhone:                     in.phone("work"),
+		ManagerEmail:                  manager,
+		ForceChangePasswordNextSignIn: in.Password == "",
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if in.Active != nil && !*in.Active {
+		if err := h.client.User.Freeze(ctx, alimail.UserLifecycleReq{BaseUserReq: alimail.BaseUserReq{ID: created.ID}, Reason: "deactivated via SCIM"}); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	h.writeUser(w, r, rv, created.ID, http.StatusCreated)
+}
+
+func (h *Handler) replaceUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	current, err := h.client.User.Get(ctx, alimail.BaseUserReq{ID: r.PathValue("id")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	in, err := decodeUser(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rv := h.newResolver()
+	if err := h.updateUser(ctx, rv, current, in); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.writeUser(w, r, rv, current.ID, http.StatusOK)
+}
+
+func (h *Handler) patchUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	current, err := h.client.User.Get(ctx, alimail.BaseUserReq{ID: r.PathValue("id")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var req PatchRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	rv := h.newResolver()
+	res, err := h.toSCIMUser(ctx, rv, *current)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	m, err := toMap(res)
+	if err == nil {
+		err = applyPatch(m, req.Operations)
+	}
+	var patched User
+	if err == nil {
+		normalizeUser(m)
+		err = fromMap(m, &patched)
+	}
+	if err == nil {
+		patched.DisplayName = patchedName(res, patched)
+		err = h.updateUser(ctx, rv, current, patched)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.writeUser(w, r, rv, current.ID, http.StatusOK)
+}
+
+// patchedName 返回 PATCH 后的姓名：displayName、name.formatted、givenName/familyName 中哪个被修改就使用哪个
+func patchedName(old, patched User) string {
+	if patched.DisplayName != old.DisplayName {
+		return patched.DisplayName
+	}
+	var on, pn Name
+	if old.Name != nil {
+		on = *old.Name
+	}
+	if patched.Name != nil {
+		pn = *patched.Name
+	}
+	switch {
+	case pn.Formatted != on.Formatted:
+		return pn.Formatted
+	case pn.GivenName != on.GivenName || pn.FamilyName != on.FamilyName:
+		return strings.TrimSpace(pn.GivenName + " " + pn.FamilyName)
+	}
+	return old.DisplayName
+}
+
+// updateUser 以 in 为用户的完整表示更新帐号：缺少的可选属性会被清空，active 为 nil 时不改变帐号状态。
+// 没有企业扩展时不修改员工编号、部门和上级，Okta 未映射这些属性时 PUT 请求不会带上扩展。
+func (h *Handler) updateUser(ctx context.Context, rv *resolver, current *alimail.User, in User) error {
+	if in.UserName != "" && !strings.EqualFold(in.UserName, current.Email) {
+		return badRequest(scimTypeMutability, "userName can't be changed")
+	}
+	patch := alimail.PatchUserReq{BaseUserReq: alimail.BaseUserReq{ID: current.ID}}
+	changed := false
+	str := func(dst **string, old, new string) {
+		if new != old {
+			*dst = alimail.Ptr(new)
+			changed = true
+		}
+	}
+	if name := in.fullName(); name != "" {
+		str(&patch.Name, current.Name, name)
+	}
+	str(&patch.Nickname, current.Nickname, in.NickName)
+	str(&patch.JobTitle, current.JobTitle, in.Title)
+	str(&patch.Phone, current.Phone, in.phone("mobile"))
+	str(&patch.WorkPhone, current.WorkPhone, in.phone("work"))
+	if in.Enterprise != nil {
+		if err := h.updateEnterprise(ctx, rv, current, *in.Enterprise, &patch); err != nil {
+			return err
+		}
+		changed = changed || patch.EmployeeNo != nil || patch.DepartmentIds != nil || patch.ManagerEmail != nil
+	}
+	if changed {
+		if _, err := h.client.User.Patch(ctx, patch); err != nil {
+			return err
+		}
+	}
+
+	if in.Active != nil && *in.Active != (current.Status != alimail.FREEZE) {
+		req := alimail.UserLifecycleReq{BaseUserReq: alimail.BaseUserReq{ID: current.ID}}
+		var err error
+		if *in.Active {
+			req.Reason = "reactivated via SCIM"
+			err = h.client.User.Unfreeze(ctx, req)
+		} else {
+			req.Reason = "deactivated via SCIM"
+			err = h.client.User.Freeze(ctx, req)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if in.Password != "" {
+		return h.client.User.ResetPassword(ctx, alimail.ResetUserPasswordReq{
+			BaseUserReq: alimail.BaseUserReq{ID: current.ID},
+			Password:    in.Password,
+		})
+	}
+	return nil
+}
+
+// updateEnterprise 将企业扩展中变化的员工编号、部门和上级写入 patch
+func (h *Handler) updateEnterprise(ctx context.Context, rv *resolver, current *alimail.User, ext EnterpriseUser, patch *alimail.PatchUserReq) error {
+	if ext.EmployeeNumber != current.EmployeeNo {
+		patch.EmployeeNo = alimail.Ptr(ext.EmployeeNumber)
+	}
+	// 没有提供部门时保持不变，避免 PUT 时把用户移到根部门
+	if want := strings.Join(alimail.SplitDepartmentPath(ext.Department), "/"); want != "" {
+		var currentPath string
+		if len(current.DepartmentIds) > 0 {
+			p, err := rv.departmentPath(ctx, current.DepartmentIds[0])
+			if err != nil {
+				return err
+			}
+			currentPath = p
+		}
+		if want != currentPath {
+			deptID, err := h.client.Department.EnsurePath(ctx, want)
+			if err != nil {
+				return err
+			}
+			patch.DepartmentIds = &[]string{deptID}
+		}
+	}
+	var managerID string
+	if ext.Manager != nil {
+		managerID = ext.Manager.Value
+	}
+	if managerID != current.ManagerInfo.ID {
+		manager := ""
+		if managerID != "" {
+			emails, err := rv.userEmails(ctx, []string{managerID})
+			if err != nil {
+				return err
+			}
+			manager = emails[0]
+		}
+		patch.ManagerEmail = alimail.Ptr(manager)
+	}
+	return nil
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req := alimail.BaseUserReq{ID: r.PathValue("id")}
+	var err error
+	if h.freezeOnDelete {
+		err = h.client.User.Freeze(ctx, alimail.UserLifecycleReq{BaseUserReq: req, Reason: "deprovisioned via SCIM"})
+	} else {
+		err = h.client.User.Delete(ctx, req)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}